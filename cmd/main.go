@@ -5,9 +5,13 @@ import (
 	"path"
 	"runtime"
 
+	"github.com/TimeTracker-Effective-Mobile/internal/client/people"
 	_ "github.com/TimeTracker-Effective-Mobile/internal/model"
 	"github.com/TimeTracker-Effective-Mobile/internal/repository"
 	"github.com/TimeTracker-Effective-Mobile/internal/router"
+	"github.com/TimeTracker-Effective-Mobile/internal/service/auth"
+	"github.com/TimeTracker-Effective-Mobile/internal/service/enrichment"
+	"github.com/TimeTracker-Effective-Mobile/internal/service/schedule"
 	"github.com/TimeTracker-Effective-Mobile/internal/service/task"
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -25,6 +29,11 @@ import (
 
 //	@securityDefinitions.basic	BasicAuth
 
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
+//	@description				"Bearer " followed by an access token from /auth/login or an API key from /api-keys.
+
 // @externalDocs.description	OpenAPI
 // @externalDocs.url			https://swagger.io/resources/open-api/
 
@@ -35,8 +44,38 @@ func main() {
 		logrus.Fatalf(".env file not found.")
 	}
 	repository := repository.New()
-	taskService := task.New(repository)
-	router := router.New(taskService)
+	peopleConfig, err := people.ConfigFromEnv()
+	if err != nil {
+		logrus.Fatalf(err.Error())
+	}
+	enrichmentConfig, err := enrichment.ConfigFromEnv()
+	if err != nil {
+		logrus.Fatalf(err.Error())
+	}
+	cacheConfig, err := people.CacheConfigFromEnv()
+	if err != nil {
+		logrus.Fatalf(err.Error())
+	}
+	// Background work such as enrichment sees all organizations.
+	unscoped := repository.Unscoped()
+	peopleClient := people.NewCached(people.New(peopleConfig), unscoped, cacheConfig)
+	enrichmentPool := enrichment.New(unscoped, peopleClient, enrichmentConfig)
+	enrichmentPool.Start()
+	taskService := task.New(repository, peopleClient, enrichmentPool)
+	scheduleService := schedule.New(repository)
+	authService := auth.New(repository)
+	if err := authService.Bootstrap(); err != nil {
+		logrus.Fatalf(err.Error())
+	}
+	// Requests only reach the data of the caller's organization.
+	tenants := func(orgId int) router.Services {
+		scoped := repository.WithTenant(orgId)
+		return router.Services{
+			Tasks:     taskService.WithStorage(scoped),
+			Schedules: scheduleService.WithStorage(scoped),
+		}
+	}
+	router := router.New(tenants, authService)
 	router.StartServer()
 }
 
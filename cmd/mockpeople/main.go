@@ -0,0 +1,133 @@
+// Command mockpeople serves the /info?passportSerie=&passportNumber= contract
+// of the external people-info API from a fixture file, so that user creation
+// can be exercised locally without the real EXTERNAL_USER_API.
+//
+//	go run ./cmd/mockpeople -addr :8081 -latency 300ms
+//	EXTERNAL_USER_API=http://localhost:8081
+//
+// -mode switches every response to notfound, error or malformed. A single
+// request can pick a mode or latency with the X-Mock-Mode and X-Mock-Latency
+// headers.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	modeOK        = "ok"
+	modeNotFound  = "notfound"
+	modeError     = "error"
+	modeMalformed = "malformed"
+)
+
+type person struct {
+	PassportSerie  string `json:"passportSerie,omitempty"`
+	PassportNumber string `json:"passportNumber,omitempty"`
+	Name           string `json:"name"`
+	Surname        string `json:"surname"`
+	Patronymic     string `json:"patronymic,omitempty"`
+	Address        string `json:"address"`
+}
+
+type server struct {
+	people  map[string]person
+	mode    string
+	latency time.Duration
+}
+
+func main() {
+	addr := flag.String("addr", ":8081", "listen address")
+	fixtures := flag.String("fixtures", "cmd/mockpeople/fixtures.json", "JSON file with people")
+	mode := flag.String("mode", modeOK, "response mode: ok, notfound, error or malformed")
+	latency := flag.Duration("latency", 0, "delay before every response")
+	flag.Parse()
+
+	if !validMode(*mode) {
+		logrus.Fatalf("unknown mode %q", *mode)
+	}
+	people, err := loadFixtures(*fixtures)
+	if err != nil {
+		logrus.Fatalf(err.Error())
+	}
+	srv := &server{people: people, mode: *mode, latency: *latency}
+	http.HandleFunc("/info", srv.info)
+	logrus.Infof("mock people API with %d people listening on %s", len(people), *addr)
+	logrus.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func loadFixtures(path string) (map[string]person, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	list := []person{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	people := make(map[string]person, len(list))
+	for _, p := range list {
+		people[p.PassportSerie+" "+p.PassportNumber] = p
+	}
+	return people, nil
+}
+
+func (s *server) info(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	mode, latency := s.mode, s.latency
+	if val := r.Header.Get("X-Mock-Mode"); validMode(val) {
+		mode = val
+	}
+	if val, err := time.ParseDuration(r.Header.Get("X-Mock-Latency")); err == nil {
+		latency = val
+	}
+	select {
+	case <-time.After(latency):
+	case <-r.Context().Done():
+		return
+	}
+
+	serie, number := r.URL.Query().Get("passportSerie"), r.URL.Query().Get("passportNumber")
+	logrus.Debugf("info %s %s mode=%s", serie, number, mode)
+	switch mode {
+	case modeError:
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	case modeNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case modeMalformed:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "Broken", "surname": `))
+		return
+	}
+	if serie == "" || number == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	p, ok := s.people[serie+" "+number]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	p.PassportSerie, p.PassportNumber = "", ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+func validMode(mode string) bool {
+	switch mode {
+	case modeOK, modeNotFound, modeError, modeMalformed:
+		return true
+	}
+	return false
+}
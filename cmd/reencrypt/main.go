@@ -0,0 +1,33 @@
+// Command reencrypt rotates the encryption of personal data. It rewraps every
+// encrypted passport number, address and cached people API response with
+// ENCRYPTION_ACTIVE_KEY and recomputes the passport blind indexes.
+//
+// To rotate a key, add the new key to ENCRYPTION_KEYS, make it active and
+// restart the servers, run
+//
+//	go run ./cmd/reencrypt
+//
+// and then remove the old key. To rotate the blind index key, move the old
+// key to ENCRYPTION_PREVIOUS_INDEX_KEY, set ENCRYPTION_INDEX_KEY to the new
+// one and restart the servers, run the command, and then unset
+// ENCRYPTION_PREVIOUS_INDEX_KEY. Lookups by passport match either index in
+// between, and rows edited while the command runs are retried rather than
+// overwritten, so the servers can keep taking requests throughout.
+package main
+
+import (
+	"github.com/TimeTracker-Effective-Mobile/internal/repository/postgres"
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		logrus.Fatalf(".env file not found.")
+	}
+	count, err := postgres.New().Unscoped().Reencrypt()
+	if err != nil {
+		logrus.Fatalf("re-encryption stopped after %d users: %s", count, err)
+	}
+	logrus.Infof("re-encrypted %d users", count)
+}
@@ -0,0 +1,204 @@
+package people
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+type CacheConfig struct {
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	MaxEntries  int
+	Persist     bool
+}
+
+// cacheStore persists lookups so they survive restarts.
+type cacheStore interface {
+	GetPeopleCacheEntry(passportNumber string) (model.PeopleCacheEntry, bool, error)
+	SavePeopleCacheEntry(entry model.PeopleCacheEntry) error
+	DeletePeopleCacheEntry(passportNumber string) error
+}
+
+type lookup interface {
+	GetInfo(ctx context.Context, series, number string) (Person, error)
+}
+
+type cacheEntry struct {
+	person    Person
+	notFound  bool
+	expiresAt time.Time
+}
+
+// CachedClient keeps successful lookups for TTL and 404s for NegativeTTL in
+// memory and, when persistence is enabled, in the store.
+type CachedClient struct {
+	client  lookup
+	store   cacheStore
+	config  CacheConfig
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// CacheConfigFromEnv reads PEOPLE_CACHE_TTL, PEOPLE_CACHE_NEGATIVE_TTL,
+// PEOPLE_CACHE_MAX_ENTRIES and PEOPLE_CACHE_PERSIST.
+func CacheConfigFromEnv() (CacheConfig, error) {
+	config := CacheConfig{
+		TTL:         24 * time.Hour,
+		NegativeTTL: time.Hour,
+		MaxEntries:  10000,
+	}
+	durations := map[string]*time.Duration{
+		"PEOPLE_CACHE_TTL":          &config.TTL,
+		"PEOPLE_CACHE_NEGATIVE_TTL": &config.NegativeTTL,
+	}
+	for key, target := range durations {
+		if val := os.Getenv(key); val != "" {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return config, fmt.Errorf("%s: %w", key, err)
+			}
+			*target = d
+		}
+	}
+	if val := os.Getenv("PEOPLE_CACHE_MAX_ENTRIES"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return config, fmt.Errorf("PEOPLE_CACHE_MAX_ENTRIES: must be a non-negative number")
+		}
+		config.MaxEntries = n
+	}
+	if val := os.Getenv("PEOPLE_CACHE_PERSIST"); val != "" {
+		persist, err := strconv.ParseBool(val)
+		if err != nil {
+			return config, fmt.Errorf("PEOPLE_CACHE_PERSIST: %w", err)
+		}
+		config.Persist = persist
+	}
+	return config, nil
+}
+
+func NewCached(client lookup, store cacheStore, config CacheConfig) *CachedClient {
+	return &CachedClient{
+		client:  client,
+		store:   store,
+		config:  config,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+func (c *CachedClient) GetInfo(ctx context.Context, series, number string) (Person, error) {
+	key := series + " " + number
+	if entry, ok := c.get(key); ok {
+		if entry.notFound {
+			return Person{}, ErrNotFound
+		}
+		return entry.person, nil
+	}
+	return c.Refresh(ctx, series, number)
+}
+
+// Refresh bypasses the cache, asks the API and caches the answer.
+func (c *CachedClient) Refresh(ctx context.Context, series, number string) (Person, error) {
+	key := series + " " + number
+	person, err := c.client.GetInfo(ctx, series, number)
+	switch {
+	case err == nil:
+		c.put(key, cacheEntry{person: person, expiresAt: time.Now().Add(c.config.TTL)})
+	case errors.Is(err, ErrNotFound):
+		c.put(key, cacheEntry{notFound: true, expiresAt: time.Now().Add(c.config.NegativeTTL)})
+	}
+	return person, err
+}
+
+// Forget drops any cached lookup for the passport, in memory and in the store.
+func (c *CachedClient) Forget(series, number string) error {
+	key := series + " " + number
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+	return c.store.DeletePeopleCacheEntry(key)
+}
+
+func (c *CachedClient) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry, true
+	}
+	if !c.config.Persist {
+		return entry, false
+	}
+	stored, found, err := c.store.GetPeopleCacheEntry(key)
+	if err != nil {
+		logrus.Debug(err)
+		return entry, false
+	}
+	if !found {
+		return entry, false
+	}
+	entry = cacheEntry{notFound: stored.NotFound, expiresAt: stored.ExpiresAt}
+	if !stored.NotFound {
+		if err := json.Unmarshal(stored.Payload, &entry.person); err != nil {
+			logrus.Debug(err)
+			return entry, false
+		}
+	}
+	c.remember(key, entry)
+	return entry, true
+}
+
+func (c *CachedClient) put(key string, entry cacheEntry) {
+	if !entry.expiresAt.After(time.Now()) {
+		return
+	}
+	c.remember(key, entry)
+	if !c.config.Persist {
+		return
+	}
+	stored := model.PeopleCacheEntry{PassportNumber: key, NotFound: entry.notFound, ExpiresAt: entry.expiresAt}
+	if !entry.notFound {
+		payload, err := json.Marshal(entry.person)
+		if err != nil {
+			logrus.Debug(err)
+			return
+		}
+		stored.Payload = payload
+	}
+	if err := c.store.SavePeopleCacheEntry(stored); err != nil {
+		logrus.Info(err)
+	}
+}
+
+func (c *CachedClient) remember(key string, entry cacheEntry) {
+	if c.config.MaxEntries == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.config.MaxEntries {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		// Still full: drop an arbitrary entry.
+		for k := range c.entries {
+			if len(c.entries) < c.config.MaxEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = entry
+}
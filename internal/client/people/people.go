@@ -0,0 +1,210 @@
+// Package people is the client of the external people-info API used to
+// enrich users by passport.
+package people
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNotFound    = errors.New("person not found")
+	ErrBadResponse = errors.New("people api returned an invalid response")
+	ErrUnavailable = errors.New("people api unavailable")
+	ErrTimeout     = errors.New("people api timed out")
+	ErrCircuitOpen = errors.New("people api circuit open")
+)
+
+// maxBodySize bounds how much of a response is read.
+const maxBodySize = 1 << 20
+
+type Person struct {
+	Name       string `json:"name"`
+	Surname    string `json:"surname"`
+	Patronymic string `json:"patronymic"`
+	Address    string `json:"address"`
+}
+
+type Config struct {
+	BaseURL          string
+	Timeout          time.Duration
+	Retries          int
+	Backoff          time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+type Client struct {
+	config  Config
+	http    *http.Client
+	breaker *breaker
+}
+
+// ConfigFromEnv reads EXTERNAL_USER_API and the optional EXTERNAL_USER_API_*
+// tuning variables.
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		BaseURL:          os.Getenv("EXTERNAL_USER_API"),
+		Timeout:          5 * time.Second,
+		Retries:          2,
+		Backoff:          200 * time.Millisecond,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+	durations := map[string]*time.Duration{
+		"EXTERNAL_USER_API_TIMEOUT":          &config.Timeout,
+		"EXTERNAL_USER_API_BACKOFF":          &config.Backoff,
+		"EXTERNAL_USER_API_BREAKER_COOLDOWN": &config.BreakerCooldown,
+	}
+	for key, target := range durations {
+		if val := os.Getenv(key); val != "" {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return config, fmt.Errorf("%s: %w", key, err)
+			}
+			*target = d
+		}
+	}
+	ints := map[string]*int{
+		"EXTERNAL_USER_API_RETRIES":           &config.Retries,
+		"EXTERNAL_USER_API_BREAKER_THRESHOLD": &config.BreakerThreshold,
+	}
+	for key, target := range ints {
+		if val := os.Getenv(key); val != "" {
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return config, fmt.Errorf("%s: must be a non-negative number", key)
+			}
+			*target = n
+		}
+	}
+	return config, nil
+}
+
+func New(config Config) *Client {
+	return &Client{
+		config:  config,
+		http:    &http.Client{Timeout: config.Timeout},
+		breaker: &breaker{threshold: config.BreakerThreshold, cooldown: config.BreakerCooldown},
+	}
+}
+
+// GetInfo looks a person up by passport series and number. Network errors and
+// 5xx responses are retried with exponential backoff; a 404 is returned as
+// ErrNotFound straight away.
+func (c *Client) GetInfo(ctx context.Context, series, number string) (Person, error) {
+	if !c.breaker.allow() {
+		return Person{}, ErrCircuitOpen
+	}
+	var person Person
+	var err error
+	for attempt := 0; attempt <= c.config.Retries; attempt++ {
+		if attempt > 0 {
+			if waitErr := c.wait(ctx, attempt); waitErr != nil {
+				err = waitErr
+				break
+			}
+		}
+		person, err = c.get(ctx, series, number)
+		if !retryable(err) {
+			break
+		}
+		logrus.Debugf("people api attempt %d failed: %s", attempt+1, err.Error())
+	}
+	if ctx.Err() != nil {
+		// The caller gave up, which says nothing about the API.
+		c.breaker.release()
+		return person, err
+	}
+	c.breaker.record(!retryable(err))
+	return person, err
+}
+
+// Refresh is GetInfo; the plain client keeps no cache.
+func (c *Client) Refresh(ctx context.Context, series, number string) (Person, error) {
+	return c.GetInfo(ctx, series, number)
+}
+
+func (c *Client) get(ctx context.Context, series, number string) (Person, error) {
+	var person Person
+	query := url.Values{"passportSerie": {series}, "passportNumber": {number}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.BaseURL+"/info?"+query.Encode(), nil)
+	if err != nil {
+		return person, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if isTimeout(err) {
+			return person, fmt.Errorf("%w: %s", ErrTimeout, err.Error())
+		}
+		return person, fmt.Errorf("%w: %s", ErrUnavailable, err.Error())
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return person, ErrNotFound
+	case resp.StatusCode == http.StatusGatewayTimeout:
+		return person, fmt.Errorf("%w: status %d", ErrTimeout, resp.StatusCode)
+	case resp.StatusCode >= 500:
+		return person, fmt.Errorf("%w: status %d", ErrUnavailable, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return person, fmt.Errorf("%w: status %d", ErrBadResponse, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		if isTimeout(err) {
+			return person, fmt.Errorf("%w: %s", ErrTimeout, err.Error())
+		}
+		return person, fmt.Errorf("%w: %s", ErrUnavailable, err.Error())
+	}
+	if err := json.Unmarshal(body, &person); err != nil {
+		return person, fmt.Errorf("%w: %s", ErrBadResponse, err.Error())
+	}
+	if person.Name == "" || person.Surname == "" {
+		return person, fmt.Errorf("%w: name and surname are required", ErrBadResponse)
+	}
+	return person, nil
+}
+
+func (c *Client) wait(ctx context.Context, attempt int) error {
+	delay := c.config.Backoff << (attempt - 1)
+	if c.config.Backoff > 0 {
+		delay += time.Duration(rand.Int63n(int64(c.config.Backoff)))
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %s", ErrTimeout, ctx.Err().Error())
+		}
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func retryable(err error) bool {
+	return errors.Is(err, ErrUnavailable) || errors.Is(err, ErrTimeout)
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
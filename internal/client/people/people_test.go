@@ -0,0 +1,210 @@
+package people
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// server answers with the given statuses in turn, repeating the last one,
+// and counts the requests it gets.
+func server(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	calls := &atomic.Int32{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		status := statuses[min(n, len(statuses)-1)]
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			fmt.Fprint(w, `{"name":"Ivan","surname":"Ivanov","address":"Moscow"}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, calls
+}
+
+func testClient(url string, retries, threshold int) *Client {
+	return New(Config{
+		BaseURL:          url,
+		Timeout:          time.Second,
+		Retries:          retries,
+		Backoff:          time.Millisecond,
+		BreakerThreshold: threshold,
+		BreakerCooldown:  time.Hour,
+	})
+}
+
+func TestGetInfoRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		wantErr  error
+		wantCall int32
+	}{
+		{"ok", []int{200}, 2, nil, 1},
+		{"retried 5xx", []int{500, 503, 200}, 2, nil, 3},
+		{"retries exhausted", []int{500}, 2, ErrUnavailable, 3},
+		{"gateway timeout retried", []int{504, 200}, 1, nil, 2},
+		{"no retries", []int{500, 200}, 0, ErrUnavailable, 1},
+		{"not found is final", []int{404, 200}, 2, ErrNotFound, 1},
+		{"client error is final", []int{400, 200}, 2, ErrBadResponse, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := server(t, tt.statuses...)
+			person, err := testClient(srv.URL, tt.retries, 0).GetInfo(context.Background(), "1234", "567890")
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && person.Surname != "Ivanov" {
+				t.Errorf("person = %+v", person)
+			}
+			if got := calls.Load(); got != tt.wantCall {
+				t.Errorf("calls = %d, want %d", got, tt.wantCall)
+			}
+		})
+	}
+}
+
+func TestGetInfoInvalidBody(t *testing.T) {
+	bodies := []string{`not json`, `{"name":"Ivan"}`, `{}`}
+	for _, body := range bodies {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		}))
+		_, err := testClient(srv.URL, 2, 0).GetInfo(context.Background(), "1234", "567890")
+		srv.Close()
+		if !errors.Is(err, ErrBadResponse) {
+			t.Errorf("body %q: err = %v, want ErrBadResponse", body, err)
+		}
+	}
+}
+
+func TestGetInfoTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+		}
+	}))
+	defer srv.Close()
+	client := testClient(srv.URL, 0, 0)
+	client.http.Timeout = 20 * time.Millisecond
+	if _, err := client.GetInfo(context.Background(), "1234", "567890"); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+}
+
+func TestGetInfoContextCancelledDuringBackoff(t *testing.T) {
+	srv, calls := server(t, 500)
+	client := testClient(srv.URL, 5, 0)
+	client.config.Backoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetInfo(ctx, "1234", "567890"); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	srv, calls := server(t, 500, 500, 500, 200)
+	client := testClient(srv.URL, 0, 2)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetInfo(ctx, "1234", "567890"); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("call %d: err = %v, want ErrUnavailable", i, err)
+		}
+	}
+	if _, err := client.GetInfo(ctx, "1234", "567890"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open: err = %v, want ErrCircuitOpen", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("calls = %d, want 2 while open", got)
+	}
+
+	// After the cooldown one trial goes through; it fails and reopens.
+	client.breaker.openUntil = time.Now().Add(-time.Second)
+	if _, err := client.GetInfo(ctx, "1234", "567890"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("trial: err = %v, want ErrUnavailable", err)
+	}
+	if _, err := client.GetInfo(ctx, "1234", "567890"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("reopened: err = %v, want ErrCircuitOpen", err)
+	}
+
+	// A successful trial closes it again.
+	client.breaker.openUntil = time.Now().Add(-time.Second)
+	if _, err := client.GetInfo(ctx, "1234", "567890"); err != nil {
+		t.Fatalf("trial: %v", err)
+	}
+	if _, err := client.GetInfo(ctx, "1234", "567890"); err != nil {
+		t.Fatalf("closed: %v", err)
+	}
+}
+
+func TestBreakerSingleTrial(t *testing.T) {
+	b := &breaker{threshold: 1, cooldown: time.Hour}
+	b.record(false)
+	b.openUntil = time.Now().Add(-time.Second)
+	if !b.allow() {
+		t.Fatal("first trial refused")
+	}
+	if b.allow() {
+		t.Fatal("second trial allowed while the first is running")
+	}
+}
+
+func TestCancelledLookupIsNotRecorded(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+	}{
+		{"cancelled", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			return ctx, cancel
+		}},
+		{"deadline exceeded", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 50*time.Millisecond)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := server(t, 500)
+			client := testClient(srv.URL, 0, 2)
+			if _, err := client.GetInfo(context.Background(), "1234", "567890"); !errors.Is(err, ErrUnavailable) {
+				t.Fatalf("err = %v, want ErrUnavailable", err)
+			}
+
+			// The caller gives up during the backoff before the retry.
+			client.config.Retries = 1
+			client.config.Backoff = time.Hour
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			if _, err := client.GetInfo(ctx, "1234", "567890"); err == nil {
+				t.Fatal("lookup succeeded")
+			}
+			if client.breaker.failures != 1 || client.breaker.trial {
+				t.Errorf("failures = %d, trial = %v, want 1 and false", client.breaker.failures, client.breaker.trial)
+			}
+		})
+	}
+}
+
+func TestNotFoundDoesNotTripBreaker(t *testing.T) {
+	srv, _ := server(t, 404)
+	client := testClient(srv.URL, 0, 1)
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetInfo(context.Background(), "1234", "567890"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("call %d: err = %v, want ErrNotFound", i, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package duration
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+type Format string
+
+const (
+	Seconds Format = "seconds"
+	HHMM    Format = "hhmm"
+	ISO8601 Format = "iso8601"
+)
+
+var ErrInvalidFormat = errors.New("invalid duration format")
+
+// FormatFromQuery reads the durationFormat query parameter. An empty format
+// means the response keeps bare seconds only.
+func FormatFromQuery(query map[string][]string) (Format, error) {
+	switch f := Format(first(query, "durationFormat")); f {
+	case "", Seconds, HHMM, ISO8601:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: durationFormat must be one of seconds, hhmm, iso8601", ErrInvalidFormat)
+	}
+}
+
+// String renders seconds as "5400", "01:30" or "PT1H30M".
+func (f Format) String(seconds int) string {
+	hours, minutes, rest := seconds/3600, seconds%3600/60, seconds%60
+	switch f {
+	case HHMM:
+		return fmt.Sprintf("%02d:%02d", hours, minutes)
+	case ISO8601:
+		if seconds == 0 {
+			return "PT0S"
+		}
+		value := "PT"
+		if hours > 0 {
+			value += strconv.Itoa(hours) + "H"
+		}
+		if minutes > 0 {
+			value += strconv.Itoa(minutes) + "M"
+		}
+		if rest > 0 {
+			value += strconv.Itoa(rest) + "S"
+		}
+		return value
+	default:
+		return strconv.Itoa(seconds)
+	}
+}
+
+// Apply adds the formatted representation of the raw duration to the tasks.
+func (f Format) Apply(tasks ...*model.Task) {
+	if f == "" {
+		return
+	}
+	for _, task := range tasks {
+		task.Formatted = &model.FormattedDuration{
+			Hours:   task.Duration / 3600,
+			Minutes: task.Duration % 3600 / 60,
+			Value:   f.String(task.Duration),
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package duration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+func TestFormatString(t *testing.T) {
+	tests := []struct {
+		format  Format
+		seconds int
+		want    string
+	}{
+		{Seconds, 0, "0"},
+		{Seconds, 5400, "5400"},
+		{HHMM, 0, "00:00"},
+		{HHMM, 59, "00:00"},
+		{HHMM, 5400, "01:30"},
+		{HHMM, 100 * 3600, "100:00"},
+		{ISO8601, 0, "PT0S"},
+		{ISO8601, 59, "PT59S"},
+		{ISO8601, 3600, "PT1H"},
+		{ISO8601, 5400, "PT1H30M"},
+		{ISO8601, 3661, "PT1H1M1S"},
+		{ISO8601, 26 * 3600, "PT26H"},
+	}
+	for _, tt := range tests {
+		if got := tt.format.String(tt.seconds); got != tt.want {
+			t.Errorf("%s.String(%d) = %q, want %q", tt.format, tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestFormatFromQuery(t *testing.T) {
+	for _, value := range []string{"seconds", "hhmm", "iso8601"} {
+		format, err := FormatFromQuery(map[string][]string{"durationFormat": {value}})
+		if err != nil || string(format) != value {
+			t.Errorf("FormatFromQuery(%q) = %q, %v", value, format, err)
+		}
+	}
+	if format, err := FormatFromQuery(nil); err != nil || format != "" {
+		t.Errorf("FormatFromQuery(nil) = %q, %v, want empty format", format, err)
+	}
+	if _, err := FormatFromQuery(map[string][]string{"durationFormat": {"HH:MM"}}); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("err = %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestFormatApply(t *testing.T) {
+	task := model.Task{Duration: 5430}
+	Format("").Apply(&task)
+	if task.Formatted != nil {
+		t.Fatalf("empty format set %+v", task.Formatted)
+	}
+	HHMM.Apply(&task)
+	want := model.FormattedDuration{Hours: 1, Minutes: 30, Value: "01:30"}
+	if task.Formatted == nil || *task.Formatted != want {
+		t.Errorf("Formatted = %+v, want %+v", task.Formatted, want)
+	}
+}
@@ -0,0 +1,163 @@
+// Package duration rounds and formats tracked durations for presentation.
+// Stored durations are always raw seconds.
+package duration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+type Mode string
+
+const (
+	Up      Mode = "up"
+	Down    Mode = "down"
+	Nearest Mode = "nearest"
+)
+
+type Scope string
+
+const (
+	PerSession Scope = "session"
+	PerTask    Scope = "task"
+)
+
+var ErrInvalidRounding = errors.New("invalid rounding")
+
+// Rounding is a billing policy such as "15 minutes up per session".
+// A zero Increment disables rounding.
+type Rounding struct {
+	Increment time.Duration
+	Mode      Mode
+	Scope     Scope
+}
+
+func (r Rounding) Enabled() bool {
+	return r.Increment > 0
+}
+
+// Round applies the policy to a number of seconds.
+func (r Rounding) Round(seconds int) int {
+	step := int(r.Increment / time.Second)
+	if step <= 0 {
+		return seconds
+	}
+	rest := seconds % step
+	if rest == 0 {
+		return seconds
+	}
+	switch r.Mode {
+	case Up:
+		return seconds - rest + step
+	case Down:
+		return seconds - rest
+	default:
+		if rest*2 >= step {
+			return seconds - rest + step
+		}
+		return seconds - rest
+	}
+}
+
+// RoundingFromEnv reads the default policy from DURATION_ROUNDING_INCREMENT,
+// DURATION_ROUNDING_MODE and DURATION_ROUNDING_SCOPE.
+func RoundingFromEnv() (Rounding, error) {
+	return parse(Rounding{Mode: Nearest, Scope: PerTask},
+		os.Getenv("DURATION_ROUNDING_INCREMENT"),
+		os.Getenv("DURATION_ROUNDING_MODE"),
+		os.Getenv("DURATION_ROUNDING_SCOPE"))
+}
+
+// RoundingFromQuery overrides the defaults with the rounding, roundingMode
+// and roundingScope query parameters.
+func RoundingFromQuery(query map[string][]string, defaults Rounding) (Rounding, error) {
+	return parse(defaults, first(query, "rounding"), first(query, "roundingMode"), first(query, "roundingScope"))
+}
+
+func parse(r Rounding, increment, mode, scope string) (Rounding, error) {
+	if increment != "" {
+		step, err := parseIncrement(increment)
+		if err != nil {
+			return r, err
+		}
+		r.Increment = step
+	}
+	if mode != "" {
+		switch Mode(mode) {
+		case Up, Down, Nearest:
+			r.Mode = Mode(mode)
+		default:
+			return r, fmt.Errorf("%w: mode must be one of up, down, nearest", ErrInvalidRounding)
+		}
+	}
+	if scope != "" {
+		switch Scope(scope) {
+		case PerSession, PerTask:
+			r.Scope = Scope(scope)
+		default:
+			return r, fmt.Errorf("%w: scope must be one of session, task", ErrInvalidRounding)
+		}
+	}
+	return r, nil
+}
+
+// parseIncrement accepts Go durations ("15m", "6m") or a bare number of minutes.
+func parseIncrement(value string) (time.Duration, error) {
+	if value == "none" {
+		return 0, nil
+	}
+	if minutes, err := strconv.Atoi(value); err == nil && minutes >= 0 {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+	step, err := time.ParseDuration(value)
+	if err != nil || step < 0 || step%time.Second != 0 {
+		return 0, fmt.Errorf("%w: increment must be a whole number of minutes or a duration like 15m", ErrInvalidRounding)
+	}
+	return step, nil
+}
+
+func first(query map[string][]string, key string) string {
+	if val, ok := query[key]; ok {
+		return val[0]
+	}
+	return ""
+}
+
+// Apply sets RoundedDuration on the tasks. Per-session rounding rounds each
+// session of a task separately; time tracked before sessions were recorded
+// is rounded as one extra session.
+func (r Rounding) Apply(tasks []model.Task, sessions []model.Session, now time.Time) {
+	if !r.Enabled() {
+		return
+	}
+	byTask := map[int][]model.Session{}
+	for _, session := range sessions {
+		byTask[session.TaskId] = append(byTask[session.TaskId], session)
+	}
+	for i := range tasks {
+		rounded := r.Round(tasks[i].Duration)
+		if r.Scope == PerSession {
+			rounded, tracked := 0, 0
+			for _, session := range byTask[tasks[i].Id] {
+				end := now
+				if session.StoppedAt != nil {
+					end = *session.StoppedAt
+				}
+				seconds := int(end.Sub(session.StartedAt).Seconds())
+				tracked += seconds
+				rounded += r.Round(seconds)
+			}
+			if legacy := tasks[i].Duration - tracked; legacy > 0 {
+				rounded += r.Round(legacy)
+			}
+			tasks[i].RoundedDuration = &rounded
+			continue
+		}
+		tasks[i].RoundedDuration = &rounded
+	}
+}
@@ -0,0 +1,125 @@
+package duration
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		name     string
+		rounding Rounding
+		seconds  int
+		want     int
+	}{
+		{"disabled", Rounding{Mode: Up}, 61, 61},
+		{"zero up", Rounding{Increment: 15 * time.Minute, Mode: Up}, 0, 0},
+		{"zero nearest", Rounding{Increment: 15 * time.Minute, Mode: Nearest}, 0, 0},
+		{"exact multiple up", Rounding{Increment: 15 * time.Minute, Mode: Up}, 1800, 1800},
+		{"exact multiple down", Rounding{Increment: 15 * time.Minute, Mode: Down}, 1800, 1800},
+		{"one second over up", Rounding{Increment: 15 * time.Minute, Mode: Up}, 901, 1800},
+		{"one second short down", Rounding{Increment: 15 * time.Minute, Mode: Down}, 1799, 900},
+		{"nearest below tie", Rounding{Increment: 15 * time.Minute, Mode: Nearest}, 449, 0},
+		{"nearest tie rounds up", Rounding{Increment: 15 * time.Minute, Mode: Nearest}, 450, 900},
+		{"nearest above tie", Rounding{Increment: 15 * time.Minute, Mode: Nearest}, 1351, 1800},
+		{"odd increment tie", Rounding{Increment: 45 * time.Second, Mode: Nearest}, 22, 0},
+		{"odd increment past tie", Rounding{Increment: 45 * time.Second, Mode: Nearest}, 23, 45},
+		{"six minutes up", Rounding{Increment: 6 * time.Minute, Mode: Up}, 1, 360},
+		{"empty mode is nearest", Rounding{Increment: time.Minute}, 30, 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rounding.Round(tt.seconds); got != tt.want {
+				t.Errorf("Round(%d) = %d, want %d", tt.seconds, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundingFromQuery(t *testing.T) {
+	defaults := Rounding{Increment: 15 * time.Minute, Mode: Nearest, Scope: PerTask}
+	tests := []struct {
+		name    string
+		query   map[string][]string
+		want    Rounding
+		invalid bool
+	}{
+		{"defaults", nil, defaults, false},
+		{"none disables", map[string][]string{"rounding": {"none"}}, Rounding{Mode: Nearest, Scope: PerTask}, false},
+		{"bare minutes", map[string][]string{"rounding": {"6"}}, Rounding{Increment: 6 * time.Minute, Mode: Nearest, Scope: PerTask}, false},
+		{"zero minutes", map[string][]string{"rounding": {"0"}}, Rounding{Mode: Nearest, Scope: PerTask}, false},
+		{"go duration", map[string][]string{"rounding": {"90s"}}, Rounding{Increment: 90 * time.Second, Mode: Nearest, Scope: PerTask}, false},
+		{"mode and scope", map[string][]string{"roundingMode": {"up"}, "roundingScope": {"session"}}, Rounding{Increment: 15 * time.Minute, Mode: Up, Scope: PerSession}, false},
+		{"negative minutes", map[string][]string{"rounding": {"-5"}}, defaults, true},
+		{"negative duration", map[string][]string{"rounding": {"-5m"}}, defaults, true},
+		{"fraction of a second", map[string][]string{"rounding": {"1500ms"}}, defaults, true},
+		{"garbage", map[string][]string{"rounding": {"quarter"}}, defaults, true},
+		{"unknown mode", map[string][]string{"roundingMode": {"ceil"}}, defaults, true},
+		{"unknown scope", map[string][]string{"roundingScope": {"day"}}, defaults, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RoundingFromQuery(tt.query, defaults)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidRounding) {
+					t.Fatalf("err = %v, want ErrInvalidRounding", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	now := time.Date(2024, 7, 9, 12, 0, 0, 0, time.UTC)
+	stopped := func(minutes int) *time.Time {
+		end := now.Add(-time.Hour).Add(time.Duration(minutes) * time.Minute)
+		return &end
+	}
+	sessions := []model.Session{
+		// Two stopped sessions of 1 and 20 minutes and one running for 5.
+		{TaskId: 1, StartedAt: now.Add(-time.Hour), StoppedAt: stopped(1)},
+		{TaskId: 1, StartedAt: now.Add(-30 * time.Minute), StoppedAt: stopped(50)},
+		{TaskId: 1, StartedAt: now.Add(-5 * time.Minute)},
+	}
+	tests := []struct {
+		name     string
+		rounding Rounding
+		duration int
+		want     *int
+	}{
+		{"disabled", Rounding{Mode: Up, Scope: PerTask}, 26 * 60, nil},
+		{"task total", Rounding{Increment: 15 * time.Minute, Mode: Up, Scope: PerTask}, 26 * 60, intPtr(30 * 60)},
+		{"every session", Rounding{Increment: 15 * time.Minute, Mode: Up, Scope: PerSession}, 26 * 60, intPtr(60 * 60)},
+		{"untracked remainder is one more session", Rounding{Increment: 15 * time.Minute, Mode: Up, Scope: PerSession}, 30 * 60, intPtr(75 * 60)},
+		{"zero duration", Rounding{Increment: 15 * time.Minute, Mode: Up, Scope: PerTask}, 0, intPtr(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := []model.Task{{Id: 1, Duration: tt.duration}}
+			tt.rounding.Apply(tasks, sessions, now)
+			got := tasks[0].RoundedDuration
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("RoundedDuration = %d, want unset", *got)
+			case tt.want != nil && got == nil:
+				t.Errorf("RoundedDuration unset, want %d", *tt.want)
+			case tt.want != nil && *got != *tt.want:
+				t.Errorf("RoundedDuration = %d, want %d", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
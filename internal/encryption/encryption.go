@@ -0,0 +1,232 @@
+// Package encryption seals personal data stored in the database with envelope
+// encryption and derives blind indexes for looking it up.
+//
+// Every value is encrypted with a fresh AES-256-GCM data key, and the data key
+// is encrypted ("wrapped") with a key-encryption key from the keyring. Rotating
+// the key-encryption key only rewraps data keys. Sealed values look like
+//
+//	enc:v1:<key id>:<base64 wrapped data key>:<base64 ciphertext>
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const prefix = "enc:v1:"
+
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Keyring holds the key-encryption keys by id, the id new values are sealed
+// with and the keys for blind indexes.
+type Keyring struct {
+	keys             map[string]cipher.AEAD
+	active           string
+	indexKey         []byte
+	previousIndexKey []byte
+}
+
+// FromEnv reads ENCRYPTION_KEYS ("id:base64key,..." with 32-byte keys, as
+// generated by `openssl rand -base64 32`),
+// ENCRYPTION_ACTIVE_KEY (defaults to the first key) and ENCRYPTION_INDEX_KEY
+// (base64, at least 32 bytes). Old keys stay in ENCRYPTION_KEYS until
+// cmd/reencrypt has moved every value to the active one; likewise the old
+// index key stays in ENCRYPTION_PREVIOUS_INDEX_KEY until the indexes are
+// rebuilt, so that lookups keep finding rows not rebuilt yet.
+func FromEnv() (*Keyring, error) {
+	k := &Keyring{keys: map[string]cipher.AEAD{}}
+	keys := os.Getenv("ENCRYPTION_KEYS")
+	if keys == "" {
+		return nil, fmt.Errorf("ENCRYPTION_KEYS is not set")
+	}
+	for _, entry := range strings.Split(keys, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS: expected id:base64key, got %q", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS: key %q must be 32 bytes of base64", id)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+		if k.active == "" {
+			k.active = id
+		}
+	}
+	if active := os.Getenv("ENCRYPTION_ACTIVE_KEY"); active != "" {
+		if _, ok := k.keys[active]; !ok {
+			return nil, fmt.Errorf("ENCRYPTION_ACTIVE_KEY: %w %q", ErrUnknownKey, active)
+		}
+		k.active = active
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(os.Getenv("ENCRYPTION_INDEX_KEY"))
+	if err != nil || len(indexKey) < 32 {
+		return nil, fmt.Errorf("ENCRYPTION_INDEX_KEY must be at least 32 bytes of base64")
+	}
+	k.indexKey = indexKey
+	if previous := os.Getenv("ENCRYPTION_PREVIOUS_INDEX_KEY"); previous != "" {
+		previousKey, err := base64.StdEncoding.DecodeString(previous)
+		if err != nil || len(previousKey) < 32 {
+			return nil, fmt.Errorf("ENCRYPTION_PREVIOUS_INDEX_KEY must be at least 32 bytes of base64")
+		}
+		k.previousIndexKey = previousKey
+	}
+	return k, nil
+}
+
+// Encrypt seals value under the active key.
+func (k *Keyring) Encrypt(value string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(data, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.active], dataKey)
+	if err != nil {
+		return "", err
+	}
+	return prefix + k.active + ":" + base64.StdEncoding.EncodeToString(wrapped) + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt opens a sealed value. Values without the envelope prefix were
+// written before encryption was introduced and are returned as they are.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	id, wrapped, ciphertext, err := split(value)
+	if err != nil {
+		return "", err
+	}
+	kek, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	dataKey, err := open(kek, wrapped)
+	if err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(data, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap moves a sealed value to the active key without touching its
+// ciphertext. Plain values are encrypted.
+func (k *Keyring) Rewrap(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return k.Encrypt(value)
+	}
+	id, wrapped, ciphertext, err := split(value)
+	if err != nil {
+		return "", err
+	}
+	if id == k.active {
+		return value, nil
+	}
+	kek, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	dataKey, err := open(kek, wrapped)
+	if err != nil {
+		return "", err
+	}
+	rewrapped, err := seal(k.keys[k.active], dataKey)
+	if err != nil {
+		return "", err
+	}
+	return prefix + k.active + ":" + base64.StdEncoding.EncodeToString(rewrapped) + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// BlindIndex is a keyed hash of value that allows equality lookups and
+// unique constraints on encrypted columns without revealing the value.
+func (k *Keyring) BlindIndex(value string) string {
+	return blindIndex(k.indexKey, value)
+}
+
+// BlindIndexes returns the blind index of value and, while the index key is
+// being rotated, its index under the previous key. Lookups match either.
+func (k *Keyring) BlindIndexes(value string) []string {
+	if k.previousIndexKey == nil {
+		return []string{k.BlindIndex(value)}
+	}
+	return []string{k.BlindIndex(value), blindIndex(k.previousIndexKey, value)}
+}
+
+func blindIndex(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+func split(value string) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, fmt.Errorf("malformed encrypted value")
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	return parts[0], wrapped, ciphertext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal prepends the random nonce to the ciphertext.
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,92 @@
+package encryption
+
+import (
+	"testing"
+)
+
+const (
+	testKeys          = "old:dGVzdC1vbmx5LWVuY3J5cHRpb24ta2V5LTAwMDAwMDA=,new:dGVzdC1vbmx5LWVuY3J5cHRpb24ta2V5LTExMTExMTE="
+	testIndexKey      = "dGVzdC1vbmx5LWJsaW5kLWluZGV4LWtleS0wMDAwMDA="
+	testOtherIndexKey = "dGVzdC1vbmx5LWJsaW5kLWluZGV4LWtleS0xMTExMTE="
+)
+
+func TestFromEnvRequiresKeys(t *testing.T) {
+	tests := []struct {
+		name             string
+		keys, indexKey   string
+		previousIndexKey string
+	}{
+		{"no keys", "", testIndexKey, ""},
+		{"short key", "1:c2hvcnQ=", testIndexKey, ""},
+		{"no index key", testKeys, "", ""},
+		{"short index key", testKeys, "c2hvcnQ=", ""},
+		{"short previous index key", testKeys, testIndexKey, "c2hvcnQ="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENCRYPTION_KEYS", tt.keys)
+			t.Setenv("ENCRYPTION_ACTIVE_KEY", "")
+			t.Setenv("ENCRYPTION_INDEX_KEY", tt.indexKey)
+			t.Setenv("ENCRYPTION_PREVIOUS_INDEX_KEY", tt.previousIndexKey)
+			if _, err := FromEnv(); err == nil {
+				t.Error("FromEnv succeeded")
+			}
+		})
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEYS", testKeys)
+	t.Setenv("ENCRYPTION_ACTIVE_KEY", "old")
+	t.Setenv("ENCRYPTION_INDEX_KEY", testIndexKey)
+	old, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := old.Encrypt("1234 567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENCRYPTION_ACTIVE_KEY", "new")
+	current, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewrapped, err := current.Rewrap(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewrapped == sealed {
+		t.Fatal("value was not rewrapped")
+	}
+	if plain, err := current.Decrypt(rewrapped); err != nil || plain != "1234 567890" {
+		t.Errorf("Decrypt = %q, %v", plain, err)
+	}
+}
+
+func TestBlindIndexes(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEYS", testKeys)
+	t.Setenv("ENCRYPTION_INDEX_KEY", testOtherIndexKey)
+	t.Setenv("ENCRYPTION_PREVIOUS_INDEX_KEY", testIndexKey)
+	rotating, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENCRYPTION_INDEX_KEY", testIndexKey)
+	t.Setenv("ENCRYPTION_PREVIOUS_INDEX_KEY", "")
+	old, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := old.BlindIndexes("1234 567890"); len(got) != 1 || got[0] != old.BlindIndex("1234 567890") {
+		t.Errorf("BlindIndexes without rotation = %v", got)
+	}
+	got := rotating.BlindIndexes("1234 567890")
+	if len(got) != 2 || got[0] != rotating.BlindIndex("1234 567890") || got[1] != old.BlindIndex("1234 567890") {
+		t.Errorf("BlindIndexes during rotation = %v", got)
+	}
+	if got[0] == got[1] {
+		t.Error("index keys produce the same index")
+	}
+}
@@ -0,0 +1,26 @@
+package model
+
+const (
+	AbsenceVacation  = "vacation"
+	AbsenceSickLeave = "sick_leave"
+	AbsenceHoliday   = "holiday"
+)
+
+// Absence is a vacation or sick leave of a single user, or a public holiday
+// that applies to every user whose schedule is in Region (all users when
+// Region is empty). DateTo is inclusive.
+type Absence struct {
+	Id       int    `json:"id" example:"1"`
+	UserId   *int   `json:"user_id,omitempty" example:"1"`
+	Region   string `json:"region,omitempty" example:"RU-MOW"`
+	Kind     string `json:"kind" example:"vacation" enums:"vacation,sick_leave,holiday"`
+	DateFrom string `json:"date_from" example:"2024-07-01"`
+	DateTo   string `json:"date_to" example:"2024-07-14"`
+	Note     string `json:"note,omitempty" example:"Summer vacation"`
+} // @name Absence
+
+type AbsenceDay struct {
+	Date      string `json:"date" example:"2024-07-01"`
+	Kind      string `json:"kind" example:"vacation"`
+	AbsenceId int    `json:"absence_id" example:"1"`
+} // @name AbsenceDay
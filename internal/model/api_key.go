@@ -0,0 +1,52 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, which tells them apart from access
+// tokens in the Authorization header.
+const APIKeyPrefix = "tt_"
+
+// Scopes an API key can be granted. Logins with a password or access token
+// are not limited by scopes.
+const (
+	ScopeUsersRead      = "users:read"
+	ScopeUsersWrite     = "users:write"
+	ScopeTasksWrite     = "tasks:write"
+	ScopeReportsRead    = "reports:read"
+	ScopeSchedulesRead  = "schedules:read"
+	ScopeSchedulesWrite = "schedules:write"
+	ScopeAdmin          = "admin"
+)
+
+var Scopes = []string{
+	ScopeUsersRead,
+	ScopeUsersWrite,
+	ScopeTasksWrite,
+	ScopeReportsRead,
+	ScopeSchedulesRead,
+	ScopeSchedulesWrite,
+	ScopeAdmin,
+}
+
+// APIKey is a long-lived key for a machine client. It acts for the login
+// that created it, limited to its scopes. Only a hash of the key is stored;
+// Key is only set in the response that creates it.
+type APIKey struct {
+	Id           int        `json:"id" example:"1"`
+	CredentialId int        `json:"-"`
+	Name         string     `json:"name" example:"CI bot"`
+	Prefix       string     `json:"prefix" example:"tt_AbC12dEf"`
+	Scopes       []string   `json:"scopes" example:"tasks:write,reports:read"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" example:"2025-01-01T00:00:00Z"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty" example:"2024-07-09T18:15:32.579945Z"`
+	CreatedAt    time.Time  `json:"created_at" example:"2024-07-09T18:15:32.579945Z"`
+	Key          string     `json:"key,omitempty" example:"tt_AbC12dEfGh34iJkL56mNoP78qRsT90uVwX12yZ3a4b"`
+} // @name APIKey
+
+// HasScope reports whether the key was granted scope.
+func (k APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// Audited actions on users.
+const (
+	AuditUserCreated    = "user.created"
+	AuditUserUpdated    = "user.updated"
+	AuditUserDeleted    = "user.deleted"
+	AuditUserRestored   = "user.restored"
+	AuditUserPurged     = "user.purged"
+	AuditUserAnonymized = "user.anonymized"
+	AuditUserRefreshed  = "user.refreshed"
+	AuditUserExported   = "user.exported"
+	AuditTeamChanged    = "user.team_changed"
+	AuditSessionEdited  = "session.edited"
+	AuditLoginCreated   = "login.created"
+)
+
+// AuditRecord is an action an authenticated caller took on a user. Details
+// name what was touched, such as changed fields, never their values. UserId
+// is unset once the user is purged.
+type AuditRecord struct {
+	Id           int            `json:"id" example:"1"`
+	UserId       *int           `json:"user_id,omitempty" example:"1"`
+	Actor        string         `json:"actor" example:"admin"`
+	CredentialId *int           `json:"-"`
+	APIKeyId     *int           `json:"-"`
+	Action       string         `json:"action" example:"user.updated"`
+	Details      map[string]any `json:"details,omitempty" swaggertype:"object"`
+	CreatedAt    time.Time      `json:"created_at" example:"2024-07-09T18:15:32.579945Z"`
+} // @name AuditRecord
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// PeopleCacheEntry is a persisted people API lookup. NotFound entries cache a
+// 404 and carry no payload.
+type PeopleCacheEntry struct {
+	PassportNumber string
+	Payload        []byte
+	NotFound       bool
+	ExpiresAt      time.Time
+}
+
+type FieldChange struct {
+	Old string `json:"old" example:"Piter"`
+	New string `json:"new" example:"Saint Petersburg"`
+} // @name FieldChange
+
+type UserRefresh struct {
+	User    User                   `json:"user"`
+	Changes map[string]FieldChange `json:"changes"`
+} // @name UserRefresh
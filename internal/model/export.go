@@ -0,0 +1,20 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// UserExport is everything stored about a user. PeopleAPI is the cached
+// people API response for the user's passport, if one is still cached.
+// Audit lists what callers did to the user, this export included.
+type UserExport struct {
+	ExportedAt time.Time       `json:"exported_at" example:"2024-07-09T18:15:32.579945Z"`
+	User       User            `json:"user"`
+	Schedule   *Schedule       `json:"schedule,omitempty"`
+	Absences   []Absence       `json:"absences"`
+	Tasks      []Task          `json:"tasks"`
+	Sessions   []Session       `json:"sessions"`
+	PeopleAPI  json.RawMessage `json:"people_api,omitempty" swaggertype:"object"`
+	Audit      []AuditRecord   `json:"audit"`
+} // @name UserExport
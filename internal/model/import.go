@@ -0,0 +1,38 @@
+package model
+
+const (
+	ImportCreated          = "created"
+	ImportDuplicate        = "duplicate"
+	ImportInvalid          = "invalid"
+	ImportEnrichmentFailed = "enrichment_failed"
+	ImportError            = "error"
+)
+
+// ImportRow is a line of a bulk import. Rows with a name and surname are
+// stored as given, others are enriched from the people API.
+type ImportRow struct {
+	Line           int    `json:"-"`
+	PassportNumber string `json:"passportNumber"`
+	Name           string `json:"name,omitempty"`
+	Surname        string `json:"surname,omitempty"`
+	Patronymic     string `json:"patronymic,omitempty"`
+	Address        string `json:"address,omitempty"`
+	// Problem is set when the line could not be read.
+	Problem string `json:"-"`
+}
+
+// ImportResult is the outcome of a single row. The passport number is masked.
+type ImportResult struct {
+	Line           int    `json:"line" example:"2"`
+	PassportNumber string `json:"passportNumber" example:"**** ***890"`
+	Status         string `json:"status" example:"created" enums:"created,duplicate,invalid,enrichment_failed,error"`
+	UserId         int    `json:"user_id,omitempty" example:"1"`
+	Error          string `json:"error,omitempty" example:"person not found"`
+} // @name ImportResult
+
+type ImportReport struct {
+	DryRun bool           `json:"dry_run" example:"false"`
+	Total  int            `json:"total" example:"3"`
+	Counts map[string]int `json:"counts"`
+	Rows   []ImportResult `json:"rows"`
+} // @name ImportReport
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+type Schedule struct {
+	UserId      int    `json:"user_id" example:"1"`
+	WeeklyHours int    `json:"weekly_hours" example:"40"`
+	WorkDays    []int  `json:"work_days" example:"1,2,3,4,5"`
+	DayStart    string `json:"day_start" example:"09:00"`
+	DayEnd      string `json:"day_end" example:"18:00"`
+	Timezone    string `json:"timezone" example:"Europe/Moscow"`
+	Region      string `json:"region,omitempty" example:"RU-MOW"`
+} // @name Schedule
+
+type Session struct {
+	Id        int        `json:"id" example:"1"`
+	TaskId    int        `json:"task_id" example:"1"`
+	StartedAt time.Time  `json:"started_at" example:"2024-07-09T18:15:32.579945Z"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty" example:"2024-07-09T19:15:32.579945Z"`
+} // @name Session
+
+// OvertimeEntry holds tracked and expected seconds for one day or one week
+// (Date is the Monday of the week for weekly entries).
+type OvertimeEntry struct {
+	Date         string `json:"date" example:"2024-07-08"`
+	Tracked      int    `json:"tracked" example:"32400"`
+	Expected     int    `json:"expected" example:"28800"`
+	Overtime     int    `json:"overtime" example:"3600"`
+	Undertime    int    `json:"undertime" example:"0"`
+	OutsideHours int    `json:"outside_hours" example:"1800"`
+	Absence      string `json:"absence,omitempty" example:"vacation"`
+} // @name OvertimeEntry
+
+type OvertimeReport struct {
+	UserId   int             `json:"user_id" example:"1"`
+	DateFrom time.Time       `json:"date_from" example:"2024-07-08T00:00:00+03:00"`
+	DateTo   time.Time       `json:"date_to" example:"2024-07-15T00:00:00+03:00"`
+	Timezone string          `json:"timezone" example:"Europe/Moscow"`
+	Days     []OvertimeEntry `json:"days"`
+	Weeks    []OvertimeEntry `json:"weeks"`
+	Total    OvertimeEntry   `json:"total"`
+} // @name OvertimeReport
@@ -0,0 +1,17 @@
+package model
+
+const (
+	SearchUser = "user"
+	SearchTask = "task"
+)
+
+// SearchHit is a user or task matching a search query. Highlight is the
+// matched text with the matching words wrapped in <mark> tags.
+type SearchHit struct {
+	Type      string  `json:"type" example:"user" enums:"user,task"`
+	Id        int     `json:"id" example:"1"`
+	UserId    int     `json:"user_id" example:"1"`
+	Title     string  `json:"title" example:"Ivanov Ivan Ivanovich"`
+	Highlight string  `json:"highlight" example:"<mark>Ivan</mark> Ivanov Ivanovich Moscow"`
+	Score     float64 `json:"score" example:"0.42"`
+} // @name SearchHit
@@ -3,11 +3,19 @@ package model
 import "time"
 
 type Task struct {
-	Id        int       `json:"id" example:"1"`
-	Owner     User      `json:"user"`
-	Name      string    `json:"name" example:"Example"`
-	CreatedAt time.Time `json:"created_at" example:"2024-07-09T18:15:32.579945Z"`
-	UpdatedAt time.Time `json:"updated_at" example:"2024-07-09T18:15:32.579945Z"`
-	IsActive  bool      `json:"is_active" example:"true"`
-	Duration  int       `json:"duration" example:"120"`
+	Id              int                `json:"id" example:"1"`
+	Owner           User               `json:"user"`
+	Name            string             `json:"name" example:"Example"`
+	CreatedAt       time.Time          `json:"created_at" example:"2024-07-09T18:15:32.579945Z"`
+	UpdatedAt       time.Time          `json:"updated_at" example:"2024-07-09T18:15:32.579945Z"`
+	IsActive        bool               `json:"is_active" example:"true"`
+	Duration        int                `json:"duration" example:"120"`
+	RoundedDuration *int               `json:"rounded_duration,omitempty" example:"900"`
+	Formatted       *FormattedDuration `json:"formatted_duration,omitempty"`
 } // @name Task
+
+type FormattedDuration struct {
+	Hours   int    `json:"hours" example:"1"`
+	Minutes int    `json:"minutes" example:"30"`
+	Value   string `json:"value" example:"01:30"`
+} // @name FormattedDuration
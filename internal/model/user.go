@@ -1,10 +1,38 @@
 package model
 
+import "time"
+
+const (
+	UserActive  = "active"
+	UserPending = "pending"
+	UserFailed  = "failed"
+)
+
 type User struct {
-	Id             int    `json:"id" example:"1"`
-	PassportNumber string `json:"passportNumber" example:"1234 567890"`
-	Name           string `json:"name" example:"Petr"`
-	Surname        string `json:"surname" example:"Petr"`
-	Patronymic     string `json:"patronymic,omitempty" example:"Petr"`
-	Address        string `json:"address" example:"Piter"`
+	Id             int        `json:"id" example:"1"`
+	PassportNumber string     `json:"passportNumber" example:"1234 567890"`
+	Name           string     `json:"name" example:"Petr"`
+	Surname        string     `json:"surname" example:"Petr"`
+	Patronymic     string     `json:"patronymic,omitempty" example:"Petr"`
+	Address        string     `json:"address" example:"Piter"`
+	Timezone       string     `json:"timezone" example:"Europe/Moscow"`
+	Status         string     `json:"status" example:"active" enums:"active,pending,failed"`
+	TeamId         *int       `json:"team_id,omitempty" example:"1"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty" example:"2024-07-09T18:15:32.579945Z"`
+	AnonymizedAt   *time.Time `json:"anonymized_at,omitempty" example:"2024-07-09T18:15:32.579945Z"`
 } // @name User
+
+// RootOrganization is the organization existing data was migrated to. Its
+// admins manage all organizations.
+const RootOrganization = 1
+
+type Organization struct {
+	Id        int       `json:"id" example:"1"`
+	Name      string    `json:"name" example:"Default"`
+	CreatedAt time.Time `json:"created_at" example:"2024-07-09T18:15:32.579945Z"`
+} // @name Organization
+
+type Team struct {
+	Id   int    `json:"id" example:"1"`
+	Name string `json:"name" example:"Backend"`
+} // @name Team
@@ -0,0 +1,130 @@
+// Package pagination parses page requests and encodes the opaque cursors used
+// for keyset pagination.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	defaultLimit    = 10
+	defaultMaxLimit = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Limits bounds the page size. Default is used when the limit parameter is
+// missing and Max caps any requested limit.
+type Limits struct {
+	Default int
+	Max     int
+}
+
+// LimitsFromEnv reads PAGINATION_DEFAULT_LIMIT and PAGINATION_MAX_LIMIT.
+func LimitsFromEnv() (Limits, error) {
+	limits := Limits{Default: defaultLimit, Max: defaultMaxLimit}
+	if val := os.Getenv("PAGINATION_DEFAULT_LIMIT"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n <= 0 {
+			return limits, fmt.Errorf("PAGINATION_DEFAULT_LIMIT must be a positive number, got %q", val)
+		}
+		limits.Default = n
+	}
+	if val := os.Getenv("PAGINATION_MAX_LIMIT"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n <= 0 {
+			return limits, fmt.Errorf("PAGINATION_MAX_LIMIT must be a positive number, got %q", val)
+		}
+		limits.Max = n
+	}
+	if limits.Default > limits.Max {
+		limits.Default = limits.Max
+	}
+	return limits, nil
+}
+
+// Page is a page request. Cursor takes precedence over Offset. A zero Limit
+// asks for every row, for listings only paginated on request.
+type Page struct {
+	Limit  int
+	Offset int
+	Cursor string
+	Total  bool
+}
+
+// Result describes the returned page. NextCursor is empty on the last page
+// and Total is only set when it was requested.
+type Result struct {
+	NextCursor string
+	Total      *int
+}
+
+// FromQuery reads limit, page, offset, cursor and total from the query. Like
+// the rest of the list parameters, malformed numbers fall back to defaults.
+func FromQuery(query map[string][]string, defaultLimit int, limits Limits) Page {
+	page := Page{Limit: defaultLimit}
+	if val, ok := query["limit"]; ok {
+		if n, err := strconv.Atoi(val[0]); err == nil && n > 0 {
+			page.Limit = n
+		}
+	}
+	if page.Limit > limits.Max {
+		page.Limit = limits.Max
+	}
+	if val, ok := query["page"]; ok {
+		if n, err := strconv.Atoi(val[0]); err == nil && n > 0 {
+			page.Offset = page.Limit * (n - 1)
+		}
+	}
+	if val, ok := query["offset"]; ok {
+		if n, err := strconv.Atoi(val[0]); err == nil && n >= 0 {
+			page.Offset = n
+		}
+	}
+	if val, ok := query["cursor"]; ok {
+		page.Cursor = val[0]
+	}
+	if val, ok := query["total"]; ok {
+		page.Total, _ = strconv.ParseBool(val[0])
+	}
+	return page
+}
+
+// Requested reports whether the query asks for a page at all.
+func Requested(query map[string][]string) bool {
+	for _, key := range []string{"limit", "page", "offset", "cursor"} {
+		if _, ok := query[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Cursor points just past the last row of a page. Sort records the ordering
+// the cursor was issued for, so it can't be replayed against another one.
+type Cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func Decode(value string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
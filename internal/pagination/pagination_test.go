@@ -0,0 +1,32 @@
+package pagination
+
+import "testing"
+
+func TestFromQuery(t *testing.T) {
+	limits := Limits{Default: 10, Max: 100}
+	tests := []struct {
+		name      string
+		query     map[string][]string
+		want      Page
+		requested bool
+	}{
+		{"defaults", map[string][]string{"name": {"x"}}, Page{Limit: 10}, false},
+		{"limit", map[string][]string{"limit": {"20"}}, Page{Limit: 20}, true},
+		{"limit capped", map[string][]string{"limit": {"1000"}}, Page{Limit: 100}, true},
+		{"malformed limit", map[string][]string{"limit": {"all"}}, Page{Limit: 10}, true},
+		{"page", map[string][]string{"limit": {"20"}, "page": {"3"}}, Page{Limit: 20, Offset: 40}, true},
+		{"offset", map[string][]string{"offset": {"5"}}, Page{Limit: 10, Offset: 5}, true},
+		{"cursor", map[string][]string{"cursor": {"abc"}}, Page{Limit: 10, Cursor: "abc"}, true},
+		{"total only", map[string][]string{"total": {"true"}}, Page{Limit: 10, Total: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromQuery(tt.query, limits.Default, limits); got != tt.want {
+				t.Errorf("FromQuery() = %+v, want %+v", got, tt.want)
+			}
+			if got := Requested(tt.query); got != tt.requested {
+				t.Errorf("Requested() = %v, want %v", got, tt.requested)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Package passport parses and validates passport numbers of the form
+// "1234 567890": a 4-digit series followed by a 6-digit number.
+package passport
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	seriesLength = 4
+	numberLength = 6
+)
+
+type Passport struct {
+	Series string
+	Number string
+}
+
+// ValidationError lists problems per field: "series", "number", or
+// "passportNumber" when the value cannot be split at all.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, field := range []string{"passportNumber", "series", "number"} {
+		if msg, ok := e.Fields[field]; ok {
+			parts = append(parts, field+": "+msg)
+		}
+	}
+	return "invalid passport number: " + strings.Join(parts, "; ")
+}
+
+// Parse accepts "1234 567890", "1234567890", "12 34 567890" and
+// "1234-567890".
+func Parse(value string) (Passport, error) {
+	tokens := strings.FieldsFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-'
+	})
+	if len(tokens) == 0 {
+		return Passport{}, invalid("passportNumber", "is required")
+	}
+	digits := strings.Join(tokens, "")
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Passport{}, invalid("passportNumber", "must contain only digits")
+		}
+	}
+	if len(digits) == seriesLength+numberLength {
+		return Passport{Series: digits[:seriesLength], Number: digits[seriesLength:]}, nil
+	}
+	if len(tokens) == 2 {
+		fields := map[string]string{}
+		if len(tokens[0]) != seriesLength {
+			fields["series"] = "must be 4 digits"
+		}
+		if len(tokens[1]) != numberLength {
+			fields["number"] = "must be 6 digits"
+		}
+		return Passport{}, &ValidationError{Fields: fields}
+	}
+	return Passport{}, invalid("passportNumber", "must be a 4-digit series and a 6-digit number")
+}
+
+// String returns the canonical "1234 567890" form used for storage.
+func (p Passport) String() string {
+	return p.Series + " " + p.Number
+}
+
+// Mask hides all but the last three digits of a passport number, so
+// "1234 567890" becomes "**** ***890". Other characters are kept.
+func Mask(value string) string {
+	digits := 0
+	for _, r := range value {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	masked := []rune(value)
+	for i, r := range masked {
+		if digits <= 3 {
+			break
+		}
+		if unicode.IsDigit(r) {
+			masked[i] = '*'
+			digits--
+		}
+	}
+	return string(masked)
+}
+
+func invalid(field, msg string) *ValidationError {
+	return &ValidationError{Fields: map[string]string{field: msg}}
+}
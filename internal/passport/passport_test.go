@@ -0,0 +1,78 @@
+package passport
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   string
+		fields map[string]string
+	}{
+		{"1234 567890", "1234 567890", nil},
+		{"1234567890", "1234 567890", nil},
+		{"12 34 567890", "1234 567890", nil},
+		{"1234-567890", "1234 567890", nil},
+		{"  1234\t567890 ", "1234 567890", nil},
+		{"", "", map[string]string{"passportNumber": "is required"}},
+		{" - ", "", map[string]string{"passportNumber": "is required"}},
+		{"1234 56789O", "", map[string]string{"passportNumber": "must contain only digits"}},
+		{"１２３４ 567890", "", map[string]string{"passportNumber": "must contain only digits"}},
+		{"123 567890", "", map[string]string{"series": "must be 4 digits"}},
+		{"1234 56789", "", map[string]string{"number": "must be 6 digits"}},
+		{"12345 5678901", "", map[string]string{"series": "must be 4 digits", "number": "must be 6 digits"}},
+		{"123456789", "", map[string]string{"passportNumber": "must be a 4-digit series and a 6-digit number"}},
+		{"12345678901", "", map[string]string{"passportNumber": "must be a 4-digit series and a 6-digit number"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Parse(tt.value)
+			if tt.fields == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got.String() != tt.want {
+					t.Errorf("Parse(%q) = %q, want %q", tt.value, got.String(), tt.want)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("err = %v, want *ValidationError", err)
+			}
+			if !reflect.DeepEqual(validationErr.Fields, tt.fields) {
+				t.Errorf("fields = %v, want %v", validationErr.Fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := &ValidationError{Fields: map[string]string{"number": "must be 6 digits", "series": "must be 4 digits"}}
+	want := "invalid passport number: series: must be 4 digits; number: must be 6 digits"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"1234 567890", "**** ***890"},
+		{"1234567890", "*******890"},
+		{"890", "890"},
+		{"12", "12"},
+		{"", ""},
+		{"ab-1234", "ab-*234"},
+	}
+	for _, tt := range tests {
+		if got := Mask(tt.value); got != tt.want {
+			t.Errorf("Mask(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
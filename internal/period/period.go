@@ -0,0 +1,60 @@
+// Package period interprets report date filters in a user's timezone.
+package period
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DateLayout  = "2006-01-02"
+	localLayout = "2006-01-02T15:04:05"
+)
+
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+// Location resolves the timezone for a report: the tz query parameter wins
+// over the fallback, which is usually the user's own timezone.
+func Location(query map[string][]string, fallback string) (*time.Location, error) {
+	name := fallback
+	if val, ok := query["tz"]; ok && val[0] != "" {
+		name = val[0]
+	}
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, name)
+	}
+	return loc, nil
+}
+
+// Parse reads an RFC3339 timestamp, a local date-time or a calendar date.
+// Values without an offset are interpreted in loc. An inclusive calendar date
+// resolves to the start of the following day.
+func Parse(value string, loc *time.Location, inclusive bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(localLayout, value, loc); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(DateLayout, value, loc)
+	if err != nil {
+		return t, err
+	}
+	if inclusive {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func StartOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func StartOfWeek(t time.Time) time.Time {
+	return StartOfDay(t).AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+}
@@ -0,0 +1,146 @@
+// Package policy decides what callers may do based on their role.
+//
+// Members track time for themselves. Managers also read reports of their
+// team, edit its sessions and maintain its schedules. Admins may do
+// everything within their organization, including managing users and logins.
+// Only admins of the root organization manage organizations. Keeping callers
+// inside their organization is not up to the policy: the storage they get
+// cannot see anything else.
+package policy
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+type Action string
+
+const (
+	ManageUsers     Action = "manage users"
+	SearchUsers     Action = "search users"
+	ReadUser        Action = "read user"
+	ExportUser      Action = "export user"
+	TrackTime       Action = "track time"
+	ReadReports     Action = "read reports"
+	EditSessions    Action = "edit sessions"
+	ManageSchedules Action = "manage schedules"
+	ReadAbsences    Action = "read absences"
+	ManageAbsences  Action = "manage absences"
+
+	ManageOrganizations Action = "manage organizations"
+)
+
+// Target is the user an action concerns. TeamId is only consulted for
+// managers; actions that concern no single user take the zero Target.
+type Target struct {
+	UserId int
+	TeamId *int
+}
+
+// DeniedError tells the caller why an action was refused.
+type DeniedError struct {
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	return e.Reason
+}
+
+func deny(reason string) error {
+	return &DeniedError{Reason: reason}
+}
+
+// Authorize returns a *DeniedError unless the principal may perform the
+// action on the target.
+func Authorize(p model.Principal, action Action, target Target) error {
+	if action == ManageOrganizations {
+		if p.IsRootAdmin() {
+			return nil
+		}
+		return deny("only admins of the root organization can manage organizations")
+	}
+	if p.IsAdmin() {
+		return nil
+	}
+	self := target.UserId != 0 && p.Owns(target.UserId)
+	team := p.Role == model.RoleManager && p.TeamId != nil && target.TeamId != nil && *p.TeamId == *target.TeamId
+	switch action {
+	case ReadUser:
+		if self || team {
+			return nil
+		}
+		return deny("you can only see yourself and, as a manager, your team")
+	case ExportUser:
+		if self {
+			return nil
+		}
+		return deny("you can only export your own data")
+	case TrackTime:
+		if self {
+			return nil
+		}
+		return deny("you can only track your own tasks")
+	case ReadReports:
+		if self || team {
+			return nil
+		}
+		return deny("only the user and the managers of their team can read these reports")
+	case EditSessions:
+		if team {
+			return nil
+		}
+		return deny("only managers of the user's team can edit sessions")
+	case ManageSchedules:
+		if team {
+			return nil
+		}
+		return deny("only managers of the user's team can change schedules")
+	case ReadAbsences:
+		if p.Role == model.RoleManager && (target.UserId == 0 || team) {
+			return nil
+		}
+		if p.Role == model.RoleManager {
+			return deny("managers can only read absences of their team")
+		}
+		return deny("only managers and admins can read absences")
+	case ManageAbsences:
+		return deny("only admins can manage absences")
+	case SearchUsers:
+		return deny("only admins can search all users")
+	}
+	return deny("only admins can " + string(action))
+}
+
+// RestrictUserQuery narrows a user listing to what the principal may see:
+// managers their team, members themselves.
+func RestrictUserQuery(p model.Principal, query url.Values) error {
+	switch {
+	case p.IsAdmin():
+		return nil
+	case p.Role == model.RoleManager && p.TeamId != nil:
+		delete(query, "team_id[in]")
+		query.Set("team_id", strconv.Itoa(*p.TeamId))
+	case p.UserId != nil:
+		delete(query, "id[in]")
+		query.Set("id", strconv.Itoa(*p.UserId))
+	default:
+		return deny("you are not linked to a user")
+	}
+	return nil
+}
+
+// RestrictAbsenceQuery narrows an absence listing to what the principal may
+// see: managers the absences of their team and the holidays.
+func RestrictAbsenceQuery(p model.Principal, query url.Values) error {
+	switch {
+	case p.IsAdmin():
+		return nil
+	case p.Role == model.RoleManager && p.TeamId != nil:
+		query.Set("teamId", strconv.Itoa(*p.TeamId))
+	default:
+		return deny("only managers of a team and admins can read absences")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+func TestReadAbsences(t *testing.T) {
+	team, otherTeam := 1, 2
+	userId := 5
+	admin := model.Principal{Role: model.RoleAdmin, OrganizationId: 2}
+	manager := model.Principal{Role: model.RoleManager, TeamId: &team, OrganizationId: 2}
+	member := model.Principal{Role: model.RoleMember, UserId: &userId, TeamId: &team, OrganizationId: 2}
+
+	tests := []struct {
+		name      string
+		principal model.Principal
+		target    Target
+		allowed   bool
+	}{
+		{"admin lists", admin, Target{}, true},
+		{"admin reads any user", admin, Target{UserId: 9, TeamId: &otherTeam}, true},
+		{"manager lists", manager, Target{}, true},
+		{"manager reads own team", manager, Target{UserId: 9, TeamId: &team}, true},
+		{"manager reads other team", manager, Target{UserId: 9, TeamId: &otherTeam}, false},
+		{"manager reads user without team", manager, Target{UserId: 9}, false},
+		{"member lists", member, Target{}, false},
+		{"member reads self", member, Target{UserId: userId, TeamId: &team}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Authorize(tt.principal, ReadAbsences, tt.target)
+			if (err == nil) != tt.allowed {
+				t.Errorf("Authorize() error = %v, allowed %v", err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestRestrictAbsenceQuery(t *testing.T) {
+	team := 1
+	tests := []struct {
+		name      string
+		principal model.Principal
+		query     url.Values
+		want      url.Values
+		allowed   bool
+	}{
+		{"admin keeps query", model.Principal{Role: model.RoleAdmin}, url.Values{"teamId": {"2"}}, url.Values{"teamId": {"2"}}, true},
+		{"manager gets own team", model.Principal{Role: model.RoleManager, TeamId: &team}, url.Values{}, url.Values{"teamId": {"1"}}, true},
+		{"manager cannot pick team", model.Principal{Role: model.RoleManager, TeamId: &team}, url.Values{"teamId": {"2", "3"}}, url.Values{"teamId": {"1"}}, true},
+		{"manager without team", model.Principal{Role: model.RoleManager}, url.Values{}, url.Values{}, false},
+		{"member", model.Principal{Role: model.RoleMember, TeamId: &team}, url.Values{}, url.Values{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RestrictAbsenceQuery(tt.principal, tt.query)
+			if (err == nil) != tt.allowed {
+				t.Fatalf("RestrictAbsenceQuery() error = %v, allowed %v", err, tt.allowed)
+			}
+			if tt.query.Encode() != tt.want.Encode() {
+				t.Errorf("query = %v, want %v", tt.query, tt.want)
+			}
+		})
+	}
+}
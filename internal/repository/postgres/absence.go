@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+const absenceColumns = `id, user_id, region, kind, to_char(date_from, 'YYYY-MM-DD'), to_char(date_to, 'YYYY-MM-DD'), note`
+
+func (p *postgresql) SaveAbsence(absence *model.Absence) error {
+	query := `INSERT INTO absences (user_id, region, kind, date_from, date_to, note, organization_id) VALUES ($1, $2, $3, $4, $5, $6, $7) returning id;`
+	return p.db.QueryRow(query, absence.UserId, absence.Region, absence.Kind, absence.DateFrom, absence.DateTo, absence.Note, p.tenant()).Scan(&absence.Id)
+}
+
+func (p *postgresql) UpdateAbsence(absence model.Absence) error {
+	query := `UPDATE absences SET user_id = $1, region = $2, kind = $3, date_from = $4, date_to = $5, note = $6 WHERE id = $7 AND ` + p.inTenant("organization_id", 8) + `;`
+	_, err := p.db.Exec(query, absence.UserId, absence.Region, absence.Kind, absence.DateFrom, absence.DateTo, absence.Note, absence.Id, p.tenant())
+	return err
+}
+
+func (p *postgresql) DeleteAbsence(absenceId int) error {
+	query := `DELETE FROM absences WHERE id = $1 AND ` + p.inTenant("organization_id", 2) + `;`
+	_, err := p.db.Exec(query, absenceId, p.tenant())
+	return err
+}
+
+func (p *postgresql) AbsenceExists(absenceId int) bool {
+	query := `SELECT COUNT(*) FROM absences WHERE id = $1 AND ` + p.inTenant("organization_id", 2) + `;`
+	row := p.db.QueryRow(query, absenceId, p.tenant())
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		logrus.Debug(err)
+		return false
+	}
+	return count > 0
+}
+
+func (p *postgresql) GetAbsence(absenceId int) (model.Absence, error) {
+	query := `SELECT ` + absenceColumns + ` FROM absences WHERE id = $1 AND ` + p.inTenant("organization_id", 2) + `;`
+	return scanAbsence(p.db.QueryRow(query, absenceId, p.tenant()))
+}
+
+func (p *postgresql) GetAbsences(query map[string][]string) ([]model.Absence, error) {
+	SQLQuery := `SELECT ` + absenceColumns + ` FROM absences WHERE ` + p.inTenant("organization_id", 1)
+	args := []any{p.tenant()}
+	if val, ok := query["userId"]; ok {
+		SQLQuery += fmt.Sprintf(" AND user_id = $%d", len(args)+1)
+		args = append(args, val[0])
+	}
+	// Holidays belong to no user and stay in a team's listing.
+	if val, ok := query["teamId"]; ok {
+		SQLQuery += fmt.Sprintf(" AND (user_id IS NULL OR user_id IN (SELECT id FROM users WHERE team_id = $%d))", len(args)+1)
+		args = append(args, val[0])
+	}
+	if val, ok := query["region"]; ok {
+		SQLQuery += fmt.Sprintf(" AND region = $%d", len(args)+1)
+		args = append(args, val[0])
+	}
+	if val, ok := query["kind"]; ok {
+		SQLQuery += fmt.Sprintf(" AND kind = $%d", len(args)+1)
+		args = append(args, val[0])
+	}
+	if val, ok := query["dateFrom"]; ok {
+		SQLQuery += fmt.Sprintf(" AND date_to >= $%d", len(args)+1)
+		args = append(args, val[0])
+	}
+	if val, ok := query["dateTo"]; ok {
+		SQLQuery += fmt.Sprintf(" AND date_from <= $%d", len(args)+1)
+		args = append(args, val[0])
+	}
+	SQLQuery += " ORDER BY date_from, id;"
+	return p.queryAbsences(SQLQuery, args...)
+}
+
+// GetAbsencesForUser returns the user's own absences and the holidays of the
+// given region overlapping the calendar days [from, to].
+func (p *postgresql) GetAbsencesForUser(userId int, region string, from, to time.Time) ([]model.Absence, error) {
+	query := `SELECT ` + absenceColumns + ` FROM absences
+		WHERE (user_id = $1 OR (user_id IS NULL AND (region = '' OR region = $2))) AND date_to >= $3 AND date_from <= $4
+		AND ` + p.inTenant("organization_id", 5) + `
+		ORDER BY date_from, id;`
+	return p.queryAbsences(query, userId, region, from.Format(time.DateOnly), to.Format(time.DateOnly), p.tenant())
+}
+
+func (p *postgresql) queryAbsences(query string, args ...any) ([]model.Absence, error) {
+	absences := []model.Absence{}
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return absences, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		absence, err := scanAbsence(rows)
+		if err != nil {
+			logrus.Debug(err)
+			continue
+		}
+		absences = append(absences, absence)
+	}
+	return absences, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAbsence(row scanner) (model.Absence, error) {
+	absence := model.Absence{}
+	userId := sql.NullInt64{}
+	err := row.Scan(&absence.Id, &userId, &absence.Region, &absence.Kind, &absence.DateFrom, &absence.DateTo, &absence.Note)
+	if userId.Valid {
+		id := int(userId.Int64)
+		absence.UserId = &id
+	}
+	return absence, err
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = `id, credential_id, name, prefix, scopes, expires_at, last_used_at, created_at`
+
+func (p *postgresql) SaveAPIKey(key *model.APIKey, hash string) error {
+	query := `INSERT INTO api_keys (credential_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at;`
+	return p.db.QueryRow(query, key.CredentialId, key.Name, key.Prefix, hash, pq.Array(key.Scopes), key.ExpiresAt).Scan(&key.Id, &key.CreatedAt)
+}
+
+func (p *postgresql) GetAPIKeys(credentialId int) ([]model.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE credential_id = $1 ORDER BY id;`
+	rows, err := p.db.Query(query, credentialId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []model.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// UseAPIKey looks up an unexpired key by its hash and records that it was
+// used.
+func (p *postgresql) UseAPIKey(hash string) (model.APIKey, bool, error) {
+	query := `UPDATE api_keys SET last_used_at = now()
+		WHERE key_hash = $1 AND (expires_at IS NULL OR expires_at > now())
+		RETURNING ` + apiKeyColumns + `;`
+	key, err := scanAPIKey(p.db.QueryRow(query, hash))
+	if err == sql.ErrNoRows {
+		return key, false, nil
+	}
+	return key, err == nil, err
+}
+
+// DeleteAPIKey deletes a key of the organization; credentialId limits it to
+// the keys of one login unless it is 0.
+func (p *postgresql) DeleteAPIKey(id, credentialId, organizationId int) (bool, error) {
+	query := `DELETE FROM api_keys WHERE id = $1 AND ($2 = 0 OR credential_id = $2)
+		AND credential_id IN (SELECT id FROM credentials WHERE organization_id = $3);`
+	res, err := p.db.Exec(query, id, credentialId, organizationId)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func scanAPIKey(row scanner) (model.APIKey, error) {
+	key := model.APIKey{}
+	expiresAt := sql.NullTime{}
+	lastUsedAt := sql.NullTime{}
+	err := row.Scan(&key.Id, &key.CredentialId, &key.Name, &key.Prefix, pq.Array(&key.Scopes), &expiresAt, &lastUsedAt, &key.CreatedAt)
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	return key, err
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+// SaveAuditRecord stores the record in the organization. A record about a
+// user outside of it is refused with sql.ErrNoRows.
+func (p *postgresql) SaveAuditRecord(record *model.AuditRecord) error {
+	details, err := json.Marshal(record.Details)
+	if err != nil {
+		return err
+	}
+	if record.Details == nil {
+		details = []byte("{}")
+	}
+	query := `INSERT INTO audit_log (organization_id, user_id, actor, credential_id, api_key_id, action, details)
+		SELECT $1::int, $2::int, $3::text, $4::int, $5::int, $6::text, $7::jsonb
+		WHERE $2::int IS NULL OR EXISTS (SELECT 1 FROM users WHERE id = $2::int AND organization_id = $1::int)
+		RETURNING id, created_at;`
+	return p.db.QueryRow(query, p.tenant(), record.UserId, record.Actor, record.CredentialId, record.APIKeyId, record.Action, string(details)).
+		Scan(&record.Id, &record.CreatedAt)
+}
+
+// GetAuditRecords returns the records about the user, oldest first.
+func (p *postgresql) GetAuditRecords(userId int) ([]model.AuditRecord, error) {
+	query := `SELECT id, user_id, actor, credential_id, api_key_id, action, details, created_at FROM audit_log
+		WHERE user_id = $1 AND ` + p.inTenant("organization_id", 2) + ` ORDER BY created_at, id;`
+	records := []model.AuditRecord{}
+	rows, err := p.db.Query(query, userId, p.tenant())
+	if err != nil {
+		return records, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		record := model.AuditRecord{}
+		var user, credential, apiKey sql.NullInt64
+		var details []byte
+		err := rows.Scan(&record.Id, &user, &record.Actor, &credential, &apiKey, &record.Action, &details, &record.CreatedAt)
+		if err != nil {
+			return records, err
+		}
+		record.UserId = nullInt(user)
+		record.CredentialId = nullInt(credential)
+		record.APIKeyId = nullInt(apiKey)
+		if err := json.Unmarshal(details, &record.Details); err != nil {
+			return records, err
+		}
+		if len(record.Details) == 0 {
+			record.Details = nil
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func nullInt(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	n := int(value.Int64)
+	return &n
+}
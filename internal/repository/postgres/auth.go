@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+// credentialColumns come with the team of the login's user.
+const credentialColumns = `c.id, c.username, c.password_hash, c.user_id, c.role, c.organization_id, u.team_id, c.created_at`
+
+func (p *postgresql) GetCredential(username string) (model.Credential, bool, error) {
+	return p.getCredential(`c.username = $1`, username)
+}
+
+func (p *postgresql) GetCredentialById(id int) (model.Credential, bool, error) {
+	return p.getCredential(`c.id = $1`, id)
+}
+
+func (p *postgresql) getCredential(where string, arg any) (model.Credential, bool, error) {
+	query := `SELECT ` + credentialColumns + ` FROM credentials c LEFT JOIN users u ON u.id = c.user_id WHERE ` + where + `;`
+	credential := model.Credential{}
+	userId := sql.NullInt64{}
+	teamId := sql.NullInt64{}
+	err := p.db.QueryRow(query, arg).Scan(&credential.Id, &credential.Username, &credential.PasswordHash, &userId, &credential.Role, &credential.OrganizationId, &teamId, &credential.CreatedAt)
+	if err == sql.ErrNoRows {
+		return credential, false, nil
+	}
+	if err != nil {
+		return credential, false, err
+	}
+	if userId.Valid {
+		id := int(userId.Int64)
+		credential.UserId = &id
+	}
+	if teamId.Valid {
+		id := int(teamId.Int64)
+		credential.TeamId = &id
+	}
+	return credential, true, nil
+}
+
+func (p *postgresql) SaveCredential(credential *model.Credential) error {
+	query := `INSERT INTO credentials (username, password_hash, user_id, role, organization_id) VALUES ($1, $2, $3, $4, $5) returning id, created_at;`
+	return p.db.QueryRow(query, credential.Username, credential.PasswordHash, credential.UserId, credential.Role, credential.OrganizationId).Scan(&credential.Id, &credential.CreatedAt)
+}
+
+func (p *postgresql) SaveRefreshToken(credentialId int, hash string, expiresAt time.Time) error {
+	query := `INSERT INTO refresh_tokens (credential_id, token_hash, expires_at) VALUES ($1, $2, $3);`
+	_, err := p.db.Exec(query, credentialId, hash, expiresAt)
+	return err
+}
+
+func (p *postgresql) GetRefreshToken(hash string) (model.RefreshToken, bool, error) {
+	query := `SELECT id, credential_id, expires_at, revoked_at, logged_out FROM refresh_tokens WHERE token_hash = $1;`
+	token := model.RefreshToken{}
+	revokedAt := sql.NullTime{}
+	err := p.db.QueryRow(query, hash).Scan(&token.Id, &token.CredentialId, &token.ExpiresAt, &revokedAt, &token.LoggedOut)
+	if err == sql.ErrNoRows {
+		return token, false, nil
+	}
+	if err != nil {
+		return token, false, err
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return token, true, nil
+}
+
+// RevokeRefreshToken revokes the token if it is still live and returns the
+// credential it belongs to. Of two concurrent calls with the same token only
+// one succeeds.
+func (p *postgresql) RevokeRefreshToken(hash string) (int, bool, error) {
+	query := `UPDATE refresh_tokens SET revoked_at = now()
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > now()
+		RETURNING credential_id;`
+	credentialId := 0
+	err := p.db.QueryRow(query, hash).Scan(&credentialId)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return credentialId, err == nil, err
+}
+
+// LogOutRefreshToken revokes the token if it is still live and marks it as
+// logged out rather than rotated.
+func (p *postgresql) LogOutRefreshToken(hash string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = now(), logged_out = true WHERE token_hash = $1 AND revoked_at IS NULL;`
+	_, err := p.db.Exec(query, hash)
+	return err
+}
+
+func (p *postgresql) RevokeRefreshTokens(credentialId int) error {
+	query := `UPDATE refresh_tokens SET revoked_at = now() WHERE credential_id = $1 AND revoked_at IS NULL;`
+	_, err := p.db.Exec(query, credentialId)
+	return err
+}
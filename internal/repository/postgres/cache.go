@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/lib/pq"
+)
+
+// Cache entries are keyed by the passport blind index and their payload,
+// which holds the person's name and address, is encrypted. Entries keyed
+// under a previous index key are found until they expire.
+
+func (p *postgresql) GetPeopleCacheEntry(passportNumber string) (model.PeopleCacheEntry, bool, error) {
+	query := `SELECT payload, not_found, expires_at FROM people_cache WHERE passport_index = ANY($1) AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY passport_index = $2 DESC LIMIT 1;`
+	entry := model.PeopleCacheEntry{PassportNumber: passportNumber}
+	payload := sql.NullString{}
+	err := p.db.QueryRow(query, pq.Array(p.keys.BlindIndexes(passportNumber)), p.keys.BlindIndex(passportNumber)).Scan(&payload, &entry.NotFound, &entry.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return entry, false, nil
+	}
+	if err != nil {
+		return entry, false, err
+	}
+	if payload.Valid {
+		plain, err := p.keys.Decrypt(payload.String)
+		if err != nil {
+			return entry, false, err
+		}
+		entry.Payload = []byte(plain)
+	}
+	return entry, true, nil
+}
+
+func (p *postgresql) SavePeopleCacheEntry(entry model.PeopleCacheEntry) error {
+	payload := sql.NullString{}
+	if entry.Payload != nil {
+		sealed, err := p.keys.Encrypt(string(entry.Payload))
+		if err != nil {
+			return err
+		}
+		payload = sql.NullString{String: sealed, Valid: true}
+	}
+	query := `INSERT INTO people_cache (passport_index, payload, not_found, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (passport_index) DO UPDATE SET payload = EXCLUDED.payload, not_found = EXCLUDED.not_found, expires_at = EXCLUDED.expires_at;`
+	_, err := p.db.Exec(query, p.keys.BlindIndex(entry.PassportNumber), payload, entry.NotFound, entry.ExpiresAt)
+	return err
+}
+
+func (p *postgresql) DeletePeopleCacheEntry(passportNumber string) error {
+	query := `DELETE FROM people_cache WHERE passport_index = ANY($1);`
+	_, err := p.db.Exec(query, pq.Array(p.keys.BlindIndexes(passportNumber)))
+	return err
+}
@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	reencryptBatch    = 500
+	reencryptAttempts = 5
+)
+
+// sealUser encrypts the passport number and address and derives the blind
+// index used to look users up by passport.
+func (p *postgresql) sealUser(user model.User) (passportNumber, address, passportIndex string, err error) {
+	passportNumber, err = p.keys.Encrypt(user.PassportNumber)
+	if err != nil {
+		return "", "", "", err
+	}
+	address, err = p.keys.Encrypt(user.Address)
+	if err != nil {
+		return "", "", "", err
+	}
+	return passportNumber, address, p.keys.BlindIndex(user.PassportNumber), nil
+}
+
+func (p *postgresql) openUser(user *model.User) error {
+	var err error
+	user.PassportNumber, err = p.keys.Decrypt(user.PassportNumber)
+	if err != nil {
+		return err
+	}
+	user.Address, err = p.keys.Decrypt(user.Address)
+	return err
+}
+
+// Reencrypt moves every encrypted value to the active key and recomputes the
+// passport blind indexes, so that retired keys can be removed from
+// ENCRYPTION_KEYS afterwards. It returns the number of users rewritten.
+func (p *postgresql) Reencrypt() (int, error) {
+	count, err := p.reencryptUsers(false)
+	if err != nil {
+		return count, err
+	}
+	return count, p.reencryptPeopleCache()
+}
+
+// reencryptUsers rewrites users in batches. With onlyPlain set it only picks
+// up rows written before encryption was introduced, which have no index yet.
+// It is safe to run while the server takes requests: see reencryptUser.
+func (p *postgresql) reencryptUsers(onlyPlain bool) (int, error) {
+	query := `SELECT id, passport_number, address, coalesce(passport_index, '') FROM users WHERE id > $1 ORDER BY id LIMIT $2;`
+	if onlyPlain {
+		query = `SELECT id, passport_number, address, '' FROM users WHERE passport_index IS NULL AND id > $1 ORDER BY id LIMIT $2;`
+	}
+	count, lastId := 0, 0
+	for {
+		rows, err := p.db.Query(query, lastId, reencryptBatch)
+		if err != nil {
+			return count, err
+		}
+		batch := []sealedUser{}
+		for rows.Next() {
+			r := sealedUser{}
+			if err := rows.Scan(&r.id, &r.passportNumber, &r.address, &r.index); err != nil {
+				rows.Close()
+				return count, err
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return count, err
+		}
+		if len(batch) == 0 {
+			return count, nil
+		}
+		for _, r := range batch {
+			lastId = r.id
+			rewritten, err := p.reencryptUser(r)
+			if err != nil {
+				return count, err
+			}
+			if rewritten {
+				count++
+			}
+		}
+	}
+}
+
+// sealedUser holds the encrypted columns of a user as stored.
+type sealedUser struct {
+	id                             int
+	passportNumber, address, index string
+}
+
+// reencryptUser rewrites one user. The update only applies while the row
+// still holds the values it was computed from, so that an edit made in the
+// meantime is not reverted; the row is then read again and retried.
+func (p *postgresql) reencryptUser(r sealedUser) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		plain, err := p.keys.Decrypt(r.passportNumber)
+		if err != nil {
+			return false, err
+		}
+		passportNumber, err := p.keys.Rewrap(r.passportNumber)
+		if err != nil {
+			return false, err
+		}
+		address, err := p.keys.Rewrap(r.address)
+		if err != nil {
+			return false, err
+		}
+		index := p.keys.BlindIndex(plain)
+		if passportNumber == r.passportNumber && address == r.address && index == r.index {
+			return false, nil
+		}
+		res, err := p.db.Exec(`UPDATE users SET passport_number = $1, address = $2, passport_index = $3
+			WHERE id = $4 AND passport_number = $5 AND address = $6;`,
+			passportNumber, address, index, r.id, r.passportNumber, r.address)
+		if err != nil {
+			return false, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil || n > 0 {
+			return n > 0, err
+		}
+		if attempt == reencryptAttempts {
+			return false, fmt.Errorf("user %d keeps changing, giving up after %d attempts", r.id, attempt)
+		}
+		err = p.db.QueryRow(`SELECT passport_number, address, coalesce(passport_index, '') FROM users WHERE id = $1;`, r.id).
+			Scan(&r.passportNumber, &r.address, &r.index)
+		if err == sql.ErrNoRows {
+			// Purged in the meantime.
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+func (p *postgresql) reencryptPeopleCache() error {
+	rows, err := p.db.Query(`SELECT passport_index, payload FROM people_cache WHERE payload IS NOT NULL;`)
+	if err != nil {
+		return err
+	}
+	payloads := map[string]string{}
+	for rows.Next() {
+		var index, payload string
+		if err := rows.Scan(&index, &payload); err != nil {
+			rows.Close()
+			return err
+		}
+		payloads[index] = payload
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for index, payload := range payloads {
+		rewrapped, err := p.keys.Rewrap(payload)
+		if err != nil {
+			return err
+		}
+		if rewrapped == payload {
+			continue
+		}
+		_, err = p.db.Exec(`UPDATE people_cache SET payload = $1 WHERE passport_index = $2;`, rewrapped, index)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encryptPlainUsers encrypts rows left over from before encryption. It runs
+// on every start and does nothing once all rows are encrypted.
+func (p *postgresql) encryptPlainUsers() error {
+	count, err := p.reencryptUsers(true)
+	if count > 0 {
+		logrus.Infof("encrypted personal data of %d users", count)
+	}
+	return err
+}
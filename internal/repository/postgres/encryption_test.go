@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+func TestReencryptUserKeepsConcurrentEdit(t *testing.T) {
+	storage := openTestStorage(t)
+	scoped := storage.WithTenant(testOrganization(t, storage))
+	user := model.User{PassportNumber: testPassport(), Address: "Moscow", Timezone: "UTC", Status: model.UserActive}
+	if err := scoped.SaveUser(&user); err != nil {
+		t.Fatal(err)
+	}
+	stale := sealedUser{id: user.Id}
+	err := storage.db.QueryRow(`SELECT passport_number, address, passport_index FROM users WHERE id = $1;`, user.Id).
+		Scan(&stale.passportNumber, &stale.address, &stale.index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Make the row differ from what a new key would produce, as after rotation.
+	stale.index = "stale"
+
+	user.Address = "Kazan"
+	if err := scoped.UpdateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.reencryptUser(stale); err != nil {
+		t.Fatal(err)
+	}
+	got, err := scoped.GetUser(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Address != "Kazan" || got.PassportNumber != user.PassportNumber {
+		t.Errorf("user = %+v, want the edit kept", got)
+	}
+}
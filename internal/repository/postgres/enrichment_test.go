@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+func TestEnrichUserIsDiscardedWhenSuperseded(t *testing.T) {
+	storage := openTestStorage(t)
+	scoped := storage.WithTenant(testOrganization(t, storage))
+	pending := func() model.User {
+		user := model.User{PassportNumber: testPassport(), Timezone: "UTC", Status: model.UserPending}
+		if err := scoped.SaveUser(&user); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	lookedUp := func(user model.User) model.User {
+		user.Name, user.Surname, user.Address = "Ivan", "Ivanov", "Moscow"
+		return user
+	}
+
+	t.Run("pending", func(t *testing.T) {
+		user := pending()
+		enriched, err := scoped.EnrichUser(lookedUp(user))
+		if err != nil || !enriched {
+			t.Fatalf("EnrichUser = %v, %v", enriched, err)
+		}
+		got, err := scoped.GetUser(user.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != model.UserActive || got.Surname != "Ivanov" || got.Address != "Moscow" || got.PassportNumber != user.PassportNumber {
+			t.Errorf("user = %+v", got)
+		}
+	})
+
+	t.Run("anonymized during lookup", func(t *testing.T) {
+		user := pending()
+		if err := scoped.AnonymizeUser(user.Id); err != nil {
+			t.Fatal(err)
+		}
+		enriched, err := scoped.EnrichUser(lookedUp(user))
+		if err != nil || enriched {
+			t.Fatalf("EnrichUser = %v, %v, want superseded", enriched, err)
+		}
+		got, err := scoped.GetUser(user.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != "" || got.Surname != "" || got.Address != "" || got.AnonymizedAt == nil {
+			t.Errorf("anonymized user was filled in again: %+v", got)
+		}
+	})
+
+	t.Run("edited during lookup", func(t *testing.T) {
+		user := pending()
+		edited := user
+		edited.Name, edited.Surname, edited.Status = "Petr", "Petrov", model.UserActive
+		if err := scoped.UpdateUser(edited); err != nil {
+			t.Fatal(err)
+		}
+		enriched, err := scoped.EnrichUser(lookedUp(user))
+		if err != nil || enriched {
+			t.Fatalf("EnrichUser = %v, %v, want superseded", enriched, err)
+		}
+		got, err := scoped.GetUser(user.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Surname != "Petrov" {
+			t.Errorf("edit was overwritten: %+v", got)
+		}
+	})
+
+	t.Run("deleted during lookup", func(t *testing.T) {
+		user := pending()
+		if err := scoped.DeleteUser(user.Id); err != nil {
+			t.Fatal(err)
+		}
+		enriched, err := scoped.EnrichUser(lookedUp(user))
+		if err != nil || enriched {
+			t.Fatalf("EnrichUser = %v, %v, want superseded", enriched, err)
+		}
+		failed, err := scoped.FailPendingUser(user.Id)
+		if err != nil || failed {
+			t.Fatalf("FailPendingUser = %v, %v, want false", failed, err)
+		}
+	})
+
+	t.Run("failing a user that is no longer pending", func(t *testing.T) {
+		user := pending()
+		if _, err := scoped.EnrichUser(lookedUp(user)); err != nil {
+			t.Fatal(err)
+		}
+		failed, err := scoped.FailPendingUser(user.Id)
+		if err != nil || failed {
+			t.Fatalf("FailPendingUser = %v, %v, want false", failed, err)
+		}
+	})
+}
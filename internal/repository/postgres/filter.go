@@ -0,0 +1,232 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/validation"
+	"github.com/lib/pq"
+)
+
+type columnKind int
+
+const (
+	textColumn columnKind = iota
+	intColumn
+	// blindIndexColumn is an encrypted column looked up through a keyed hash
+	// of its value; it only supports exact matches.
+	blindIndexColumn
+	// encryptedColumn can neither be filtered nor sorted on.
+	encryptedColumn
+)
+
+type column struct {
+	name     string
+	kind     columnKind
+	nullable bool
+}
+
+// expr is the column as used for ordering and cursor comparisons. NULLs
+// would break row-wise comparisons, so nullable text sorts as an empty string
+// and nullable numbers as 0.
+func (c column) expr() string {
+	if c.nullable && c.kind == intColumn {
+		return "COALESCE(" + c.name + ", 0)"
+	}
+	if c.nullable {
+		return "COALESCE(" + c.name + ", '')"
+	}
+	return c.name
+}
+
+// filterColumns whitelists the query parameters a list endpoint can filter
+// on and maps them to columns.
+type filterColumns map[string]column
+
+var userFilterColumns = filterColumns{
+	"id":             {name: "id", kind: intColumn},
+	"passportNumber": {name: "passport_index", kind: blindIndexColumn},
+	"name":           {name: "name", kind: textColumn},
+	"surname":        {name: "surname", kind: textColumn},
+	"patronymic":     {name: "patronymic", kind: textColumn, nullable: true},
+	"address":        {name: "address", kind: encryptedColumn},
+	"timezone":       {name: "timezone", kind: textColumn},
+	"status":         {name: "status", kind: textColumn},
+	"team_id":        {name: "team_id", kind: intColumn, nullable: true},
+}
+
+var taskFilterColumns = filterColumns{
+	"id":   {name: "id", kind: intColumn},
+	"name": {name: "name", kind: textColumn},
+}
+
+// filter collects AND-ed conditions with numbered placeholders.
+//
+// Supported operators, by query parameter suffix:
+//
+//	name=Petr          exact match (repeat the parameter to match any of the values)
+//	name~=pet          case-insensitive substring
+//	name^=pe           case-insensitive prefix
+//	id[in]=1,2,3       any of a comma-separated list
+type filter struct {
+	conditions []string
+	args       []any
+	// blindIndex hashes values of blindIndexColumn columns, under every index
+	// key in use.
+	blindIndex func(string) []string
+}
+
+func newFilter(args ...any) *filter {
+	return &filter{args: args}
+}
+
+// arg binds a value and returns its placeholder.
+func (f *filter) arg(value any) string {
+	f.args = append(f.args, value)
+	return fmt.Sprintf("$%d", len(f.args))
+}
+
+func (f *filter) add(condition string) {
+	f.conditions = append(f.conditions, condition)
+}
+
+// apply adds a condition for every whitelisted parameter in query. Parameters
+// that are not filters (page, limit, ...) are ignored.
+func (f *filter) apply(query map[string][]string, columns filterColumns) error {
+	problems := map[string]string{}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	// Map order is random; sorted keys keep the generated SQL stable.
+	sort.Strings(keys)
+	for _, key := range keys {
+		values := query[key]
+		field, op := splitOperator(key)
+		col, ok := columns[field]
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if col.kind == encryptedColumn {
+			problems[key] = "filtering is not supported on encrypted fields"
+			continue
+		}
+		if op == "[in]" {
+			values = strings.Split(values[0], ",")
+		}
+		if col.kind == blindIndexColumn {
+			if op == "~" || op == "^" {
+				problems[key] = "partial matching is not supported on encrypted fields"
+				continue
+			}
+			hashed := make([]string, 0, len(values))
+			for _, value := range values {
+				hashed = append(hashed, f.blindIndex(strings.TrimSpace(value))...)
+			}
+			values = hashed
+		}
+		switch op {
+		case "=":
+			if len(values) == 1 {
+				value, err := col.parse(values[0])
+				if err != nil {
+					problems[key] = err.Error()
+					continue
+				}
+				// 0 stands for no value in nullable numbers, such as users
+				// without a team.
+				if col.nullable && value == int64(0) {
+					f.add(col.name + " IS NULL")
+					continue
+				}
+				f.add(fmt.Sprintf("%s = %s", col.name, f.arg(value)))
+				continue
+			}
+			if err := f.in(col, values); err != nil {
+				problems[key] = err.Error()
+			}
+		case "[in]":
+			if err := f.in(col, values); err != nil {
+				problems[key] = err.Error()
+			}
+		case "~", "^":
+			if col.kind != textColumn {
+				problems[key] = "partial matching is only supported for text fields"
+				continue
+			}
+			pattern := escapeLike(values[0]) + "%"
+			if op == "~" {
+				pattern = "%" + pattern
+			}
+			f.add(fmt.Sprintf("%s ILIKE %s", col.name, f.arg(pattern)))
+		}
+	}
+	if len(problems) > 0 {
+		return &validation.Error{Message: "invalid filter", Fields: problems}
+	}
+	return nil
+}
+
+func (f *filter) in(col column, values []string) error {
+	if col.kind == intColumn {
+		ids := make([]int64, 0, len(values))
+		for _, value := range values {
+			id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return fmt.Errorf("must be a list of numbers")
+			}
+			ids = append(ids, id)
+		}
+		f.add(fmt.Sprintf("%s = ANY(%s)", col.expr(), f.arg(pq.Array(ids))))
+		return nil
+	}
+	f.add(fmt.Sprintf("%s = ANY(%s)", col.name, f.arg(pq.Array(values))))
+	return nil
+}
+
+// where renders the conditions, prefixed with " WHERE ", or nothing.
+func (f *filter) where() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conditions, " AND ")
+}
+
+// and renders the conditions for appending to an existing WHERE clause.
+func (f *filter) and() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+	return " AND " + strings.Join(f.conditions, " AND ")
+}
+
+func (c column) parse(value string) (any, error) {
+	if c.kind == intColumn {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return n, nil
+	}
+	return value, nil
+}
+
+// splitOperator splits "name~" into "name" and "~". url.Values keeps the
+// operator character in front of "=" as part of the key.
+func splitOperator(key string) (string, string) {
+	switch {
+	case strings.HasSuffix(key, "[in]"):
+		return strings.TrimSuffix(key, "[in]"), "[in]"
+	case strings.HasSuffix(key, "~"):
+		return strings.TrimSuffix(key, "~"), "~"
+	case strings.HasSuffix(key, "^"):
+		return strings.TrimSuffix(key, "^"), "^"
+	}
+	return key, "="
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/pagination"
+	"github.com/TimeTracker-Effective-Mobile/internal/validation"
+	"github.com/lib/pq"
+)
+
+func TestFilterApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		query map[string][]string
+		where string
+		args  []any
+	}{
+		{"nothing", map[string][]string{"limit": {"10"}, "unknown": {"x"}}, "", nil},
+		{"exact", map[string][]string{"name": {"Petr"}}, " WHERE name = $1", []any{"Petr"}},
+		{"int", map[string][]string{"id": {"7"}}, " WHERE id = $1", []any{int64(7)}},
+		{"repeated is any", map[string][]string{"name": {"Petr", "Ivan"}}, " WHERE name = ANY($1)", []any{pq.Array([]string{"Petr", "Ivan"})}},
+		{"in list", map[string][]string{"id[in]": {"1, 2,3"}}, " WHERE id = ANY($1)", []any{pq.Array([]int64{1, 2, 3})}},
+		{"substring", map[string][]string{"name~": {"et"}}, " WHERE name ILIKE $1", []any{"%et%"}},
+		{"prefix", map[string][]string{"surname^": {"Pe"}}, " WHERE surname ILIKE $1", []any{"Pe%"}},
+		{"like is escaped", map[string][]string{"name~": {`5%_\`}}, " WHERE name ILIKE $1", []any{`%5\%\_\\%`}},
+		{
+			"conditions are AND-ed in key order",
+			map[string][]string{"surname": {"Petrov"}, "name^": {"P"}, "id[in]": {"1,2"}},
+			" WHERE id = ANY($1) AND name ILIKE $2 AND surname = $3",
+			[]any{pq.Array([]int64{1, 2}), "P%", "Petrov"},
+		},
+		{"nullable int", map[string][]string{"team_id": {"3"}}, " WHERE team_id = $1", []any{int64(3)}},
+		{"nullable int zero is null", map[string][]string{"team_id": {"0"}}, " WHERE team_id IS NULL", nil},
+		{"nullable int list with zero", map[string][]string{"team_id[in]": {"0,3"}}, " WHERE COALESCE(team_id, 0) = ANY($1)", []any{pq.Array([]int64{0, 3})}},
+		{"blind index", map[string][]string{"passportNumber": {" 1234 567890 "}}, " WHERE passport_index = $1", []any{"hash(1234 567890)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFilter()
+			f.blindIndex = func(value string) []string { return []string{"hash(" + value + ")"} }
+			if err := f.apply(tt.query, userFilterColumns); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if f.where() != tt.where {
+				t.Errorf("where = %q, want %q", f.where(), tt.where)
+			}
+			if !reflect.DeepEqual(f.args, tt.args) {
+				t.Errorf("args = %#v, want %#v", f.args, tt.args)
+			}
+		})
+	}
+}
+
+func TestFilterApplyInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		query map[string][]string
+		field string
+	}{
+		{"int", map[string][]string{"id": {"seven"}}, "id"},
+		{"int list", map[string][]string{"id[in]": {"1,x"}}, "id[in]"},
+		{"partial on int", map[string][]string{"id~": {"1"}}, "id~"},
+		{"encrypted", map[string][]string{"address": {"Piter"}}, "address"},
+		{"partial on blind index", map[string][]string{"passportNumber^": {"1234"}}, "passportNumber^"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFilter()
+			f.blindIndex = func(value string) []string { return []string{value} }
+			err := f.apply(tt.query, userFilterColumns)
+			var validationErr *validation.Error
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("err = %v, want *validation.Error", err)
+			}
+			if _, ok := validationErr.Fields[tt.field]; !ok {
+				t.Errorf("fields = %v, want a problem with %s", validationErr.Fields, tt.field)
+			}
+			if len(f.conditions) != 0 {
+				t.Errorf("conditions = %v, want none", f.conditions)
+			}
+		})
+	}
+}
+
+// Placeholders continue after arguments the query already binds, and after
+// the filters when the caller appends its own.
+func TestFilterPlaceholderNumbering(t *testing.T) {
+	f := newFilter(42)
+	if err := f.apply(map[string][]string{"name": {"Fix"}, "id[in]": {"3,4"}}, taskFilterColumns); err != nil {
+		t.Fatal(err)
+	}
+	want := " AND id = ANY($2) AND name = $3"
+	if f.and() != want {
+		t.Errorf("and = %q, want %q", f.and(), want)
+	}
+	if limit := f.arg(10); limit != "$4" {
+		t.Errorf("next placeholder = %s, want $4", limit)
+	}
+	if len(f.args) != 4 || f.args[0] != 42 {
+		t.Errorf("args = %v", f.args)
+	}
+}
+
+func TestFilterAfter(t *testing.T) {
+	keys := []sortKey{
+		{field: "surname", col: column{name: "surname", kind: textColumn}},
+		{field: "patronymic", col: column{name: "patronymic", kind: textColumn, nullable: true}, desc: true},
+		{field: "id", col: column{name: "id", kind: intColumn}},
+	}
+	cursor := pagination.Cursor{Sort: "surname,-patronymic,id", Values: []string{"Petrov", "", "9"}}.Encode()
+	f := newFilter(1)
+	if err := f.after(keys, cursor); err != nil {
+		t.Fatal(err)
+	}
+	want := "((surname > $2) OR (surname = $2 AND COALESCE(patronymic, '') < $3) OR " +
+		"(surname = $2 AND COALESCE(patronymic, '') = $3 AND id > $4))"
+	if got := strings.TrimPrefix(f.and(), " AND "); got != want {
+		t.Errorf("condition = %q\nwant        %q", got, want)
+	}
+	if !reflect.DeepEqual(f.args, []any{1, "Petrov", "", int64(9)}) {
+		t.Errorf("args = %#v", f.args)
+	}
+}
+
+func TestFilterAfterInvalidCursor(t *testing.T) {
+	keys := []sortKey{{field: "id", col: column{name: "id", kind: intColumn}}}
+	cursors := map[string]string{
+		"malformed":      "not a cursor",
+		"other sort":     pagination.Cursor{Sort: "-id", Values: []string{"1"}}.Encode(),
+		"wrong length":   pagination.Cursor{Sort: "id", Values: []string{"1", "2"}}.Encode(),
+		"non-number key": pagination.Cursor{Sort: "id", Values: []string{"x"}}.Encode(),
+	}
+	for name, cursor := range cursors {
+		f := newFilter()
+		var validationErr *validation.Error
+		if err := f.after(keys, cursor); !errors.As(err, &validationErr) {
+			t.Errorf("%s: err = %v, want *validation.Error", name, err)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	keys, err := parseSort(map[string][]string{"sort": {"-surname, name,-surname"}}, userFilterColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sortSpec(keys); got != "-surname,name,id" {
+		t.Errorf("spec = %q, want -surname,name,id", got)
+	}
+	if got := orderBy(keys); got != " ORDER BY surname DESC, name ASC, id ASC" {
+		t.Errorf("orderBy = %q", got)
+	}
+	for _, sort := range []string{"unknown", "address", "passportNumber"} {
+		if _, err := parseSort(map[string][]string{"sort": {sort}}, userFilterColumns); err == nil {
+			t.Errorf("sort %q accepted", sort)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+// SaveOrganization creates the organization together with its first admin
+// login, and reports false if the name is taken.
+func (p *postgresql) SaveOrganization(organization *model.Organization, admin *model.Credential) (bool, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	query := `INSERT INTO organizations (name) VALUES ($1) ON CONFLICT (name) DO NOTHING RETURNING id, created_at;`
+	err = tx.QueryRow(query, organization.Name).Scan(&organization.Id, &organization.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	admin.OrganizationId = organization.Id
+	query = `INSERT INTO credentials (username, password_hash, user_id, role, organization_id) VALUES ($1, $2, $3, $4, $5) returning id, created_at;`
+	err = tx.QueryRow(query, admin.Username, admin.PasswordHash, admin.UserId, admin.Role, admin.OrganizationId).Scan(&admin.Id, &admin.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (p *postgresql) GetOrganizations() ([]model.Organization, error) {
+	query := `SELECT id, name, created_at FROM organizations ORDER BY id;`
+	organizations := []model.Organization{}
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return organizations, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		organization := model.Organization{}
+		if err := rows.Scan(&organization.Id, &organization.Name, &organization.CreatedAt); err != nil {
+			return organizations, err
+		}
+		organizations = append(organizations, organization)
+	}
+	return organizations, rows.Err()
+}
+
+// UserInOrganization reports whether the user exists in the organization and
+// is not deleted.
+func (p *postgresql) UserInOrganization(userId, organizationId int) bool {
+	return p.WithTenant(organizationId).UserExists(userId)
+}
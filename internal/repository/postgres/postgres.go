@@ -4,80 +4,312 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
-	"strconv"
 	"time"
 
+	"github.com/TimeTracker-Effective-Mobile/internal/encryption"
 	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/pagination"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
+// postgresql is scoped to the organization org by WithTenant: users, tasks
+// and everything hanging off them are only read and written within it. The
+// storage returned by New is scoped to no organization and finds none of
+// them; only background jobs get to see all organizations, through Unscoped.
 type postgresql struct {
-	db *sql.DB
+	db       *sql.DB
+	keys     *encryption.Keyring
+	org      int
+	unscoped bool
 }
 
 func New() *postgresql {
+	psg, err := Open(ConnStringFromEnv(), "file://migrations")
+	if err != nil {
+		logrus.Fatalf(err.Error())
+	}
+	return psg
+}
+
+// ConnStringFromEnv builds the connection string from POSTGRES_USER,
+// POSTGRES_PASSWORD, POSTGRES_HOST, POSTGRES_PORT and POSTGRES_DATABASE.
+func ConnStringFromEnv() string {
 	usr := os.Getenv("POSTGRES_USER")
 	pass := os.Getenv("POSTGRES_PASSWORD")
 	host := os.Getenv("POSTGRES_HOST")
 	port := os.Getenv("POSTGRES_PORT")
 	dbName := os.Getenv("POSTGRES_DATABASE")
-	connStr := fmt.Sprintf("postgres://%v:%v@%v:%v/%v?sslmode=disable",
+	return fmt.Sprintf("postgres://%v:%v@%v:%v/%v?sslmode=disable",
 		usr, pass, host, port, dbName)
+}
 
+// Open connects to the database, applies the migrations found at the
+// migrations source URL and encrypts values left in plain text.
+func Open(connStr, migrations string) (*postgresql, error) {
 	db, err := sql.Open("postgres", connStr)
-
 	if err != nil {
-		logrus.Fatalf(err.Error())
+		return nil, err
 	}
-	err = db.Ping()
-	if err != nil {
-		logrus.Fatalf(err.Error())
+	if err := db.Ping(); err != nil {
+		return nil, err
 	}
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
-		logrus.Fatalf(err.Error())
+		return nil, err
 	}
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
-		"postgres", driver)
-
+	m, err := migrate.NewWithDatabaseInstance(migrations, "postgres", driver)
 	if err != nil {
-		logrus.Fatalf(err.Error())
+		return nil, err
 	}
-	err = m.Up()
-	if err != nil && err != migrate.ErrNoChange {
-		logrus.Fatalf(err.Error())
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return nil, err
 	}
-	psg := &postgresql{db: db}
-	return psg
+	keys, err := encryption.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	psg := &postgresql{db: db, keys: keys}
+	if err := psg.encryptPlainUsers(); err != nil {
+		return nil, err
+	}
+	return psg, nil
+}
+
+// WithTenant returns the storage scoped to an organization.
+func (p *postgresql) WithTenant(orgId int) *postgresql {
+	scoped := *p
+	scoped.org = orgId
+	scoped.unscoped = false
+	return &scoped
+}
+
+// Unscoped returns the storage for background jobs, which work across all
+// organizations. Requests must never get it.
+func (p *postgresql) Unscoped() *postgresql {
+	unscoped := *p
+	unscoped.org = 0
+	unscoped.unscoped = true
+	return &unscoped
+}
+
+// inTenant is the condition limiting column to the organization, with
+// tenant() in parameter n.
+func (p *postgresql) inTenant(column string, n int) string {
+	if p.unscoped {
+		return fmt.Sprintf("$%d::int IS NULL", n)
+	}
+	return fmt.Sprintf("%s = $%d", column, n)
 }
 
+// tenant is the organization rows are read and written in, and nil for
+// unscoped storage, which writes nowhere.
+func (p *postgresql) tenant() any {
+	if p.unscoped {
+		return nil
+	}
+	return p.org
+}
+
+// scope limits a filter to the organization.
+func (p *postgresql) scope(f *filter) {
+	if !p.unscoped {
+		f.add("organization_id = " + f.arg(p.org))
+	}
+}
+
+const userColumns = `id, passport_number, name, surname, patronymic, address, timezone, status, team_id, deleted_at, anonymized_at`
+
+const taskColumns = `id, owner, name, created_at, updated_at, active, duration`
+
 func (p *postgresql) SaveUser(user *model.User) error {
-	query := `INSERT INTO users (passport_number, name, surname, patronymic, address) VALUES ($1, $2, $3, $4, $5) returning id;`
-	err := p.db.QueryRow(query, user.PassportNumber, user.Name, user.Surname, user.Patronymic, user.Address).Scan(&user.Id)
+	passportNumber, address, passportIndex, err := p.sealUser(*user)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO users (passport_number, passport_index, name, surname, patronymic, address, timezone, status, organization_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) returning id;`
+	err = p.db.QueryRow(query, passportNumber, passportIndex, user.Name, user.Surname, user.Patronymic, address, user.Timezone, user.Status, p.tenant()).Scan(&user.Id)
 	return err
 }
 
 func (p *postgresql) UpdateUser(user model.User) error {
-	query := `UPDATE users SET passport_number = $1, name = $2, surname= $3, patronymic= $4, address= $5 WHERE id = $6;`
+	passportNumber, address, passportIndex, err := p.sealUser(user)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE users SET passport_number = $1, passport_index = $2, name = $3, surname= $4, patronymic= $5, address= $6, timezone = $7, status = $8 WHERE id = $9 AND ` + p.inTenant("organization_id", 10) + `;`
 
-	_, err := p.db.Exec(query, user.PassportNumber, user.Name, user.Surname, user.Patronymic, user.Address, user.Id)
+	_, err = p.db.Exec(query, passportNumber, passportIndex, user.Name, user.Surname, user.Patronymic, address, user.Timezone, user.Status, user.Id, p.tenant())
 	return err
 }
 
+// GetUser returns the user even when it is soft-deleted.
+func (p *postgresql) GetUser(userId int) (model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND ` + p.inTenant("organization_id", 2) + `;`
+	return p.scanUser(p.db.QueryRow(query, userId, p.tenant()))
+}
+
+func (p *postgresql) GetUsersByStatus(status string) ([]model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE status = $1 AND deleted_at IS NULL AND ` + p.inTenant("organization_id", 2) + ` ORDER BY id;`
+	users := []model.User{}
+	rows, err := p.db.Query(query, status, p.tenant())
+	if err != nil {
+		return users, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user, err := p.scanUser(rows)
+		if err != nil {
+			logrus.Debug(err)
+			continue
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// EnrichUser stores the profile looked up for a pending user and activates
+// it. Only the looked-up fields are written, and only while the user is still
+// pending, not anonymized and not deleted; edits activate a pending user, so
+// false means the user changed meanwhile and the lookup is superseded.
+func (p *postgresql) EnrichUser(user model.User) (bool, error) {
+	address, err := p.keys.Encrypt(user.Address)
+	if err != nil {
+		return false, err
+	}
+	query := `UPDATE users SET name = $1, surname = $2, patronymic = $3, address = $4, status = $5
+		WHERE id = $6 AND status = $7 AND anonymized_at IS NULL AND deleted_at IS NULL AND ` + p.inTenant("organization_id", 8) + `;`
+	res, err := p.db.Exec(query, user.Name, user.Surname, user.Patronymic, address, model.UserActive, user.Id, model.UserPending, p.tenant())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FailPendingUser marks a user whose enrichment gave up as failed, unless it
+// is no longer pending or was deleted.
+func (p *postgresql) FailPendingUser(userId int) (bool, error) {
+	query := `UPDATE users SET status = $1 WHERE id = $2 AND status = $3 AND deleted_at IS NULL AND ` + p.inTenant("organization_id", 4) + `;`
+	res, err := p.db.Exec(query, model.UserFailed, userId, model.UserPending, p.tenant())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetUserByPassport includes soft-deleted users, which keep their passport
+// registered until they are purged.
+func (p *postgresql) GetUserByPassport(passportNumber string) (model.User, bool, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE passport_index = ANY($1) AND ` + p.inTenant("organization_id", 2) + ` LIMIT 1;`
+	user, err := p.scanUser(p.db.QueryRow(query, pq.Array(p.keys.BlindIndexes(passportNumber)), p.tenant()))
+	if err == sql.ErrNoRows {
+		return user, false, nil
+	}
+	return user, err == nil, err
+}
+
+// scanUser reads a row of userColumns and decrypts it.
+func (p *postgresql) scanUser(row scanner) (model.User, error) {
+	user := model.User{}
+	patronymic := sql.NullString{}
+	deletedAt := sql.NullTime{}
+	anonymizedAt := sql.NullTime{}
+	teamId := sql.NullInt64{}
+	err := row.Scan(&user.Id, &user.PassportNumber, &user.Name, &user.Surname, &patronymic, &user.Address, &user.Timezone, &user.Status, &teamId, &deletedAt, &anonymizedAt)
+	user.Patronymic = patronymic.String
+	if teamId.Valid {
+		id := int(teamId.Int64)
+		user.TeamId = &id
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+	if anonymizedAt.Valid {
+		user.AnonymizedAt = &anonymizedAt.Time
+	}
+	if err != nil {
+		return user, err
+	}
+	return user, p.openUser(&user)
+}
+
+// DeleteUser soft-deletes the user. Its tasks and sessions are kept for
+// payroll history; PurgeUser removes them for good.
 func (p *postgresql) DeleteUser(userId int) error {
-	query := `DELETE FROM users WHERE id = $1;`
-	_, err := p.db.Exec(query, userId)
+	query := `UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL AND ` + p.inTenant("organization_id", 2) + `;`
+	_, err := p.db.Exec(query, userId, p.tenant())
 	return err
 }
 
+// RestoreUser undoes DeleteUser and reports whether there was a deleted user
+// to restore.
+func (p *postgresql) RestoreUser(userId int) (bool, error) {
+	query := `UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND ` + p.inTenant("organization_id", 2) + `;`
+	res, err := p.db.Exec(query, userId, p.tenant())
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// PurgeUser deletes the user, deleted or not, together with all of its data
+// and reports whether the user existed.
+func (p *postgresql) PurgeUser(userId int) (bool, error) {
+	query := `DELETE FROM users WHERE id = $1 AND ` + p.inTenant("organization_id", 2) + `;`
+	res, err := p.db.Exec(query, userId, p.tenant())
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// AnonymizeUser scrubs the user's personal fields and absence notes. The
+// passport is replaced with a placeholder unique to the user, since the
+// column is unique. Tasks and sessions are kept for accounting. Pending
+// enrichment is cancelled: the user leaves pending, and EnrichUser discards
+// lookups still in flight for anonymized users.
+func (p *postgresql) AnonymizeUser(userId int) error {
+	passportNumber, address, passportIndex, err := p.sealUser(model.User{PassportNumber: fmt.Sprintf("anonymized %d", userId)})
+	if err != nil {
+		return err
+	}
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET passport_number = $1, passport_index = $2, name = '', surname = '', patronymic = NULL, address = $3,
+		status = CASE WHEN status = 'pending' THEN 'failed' ELSE status END, anonymized_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND ` + p.inTenant("organization_id", 5) + `;`
+	res, err := tx.Exec(query, passportNumber, passportIndex, address, userId, p.tenant())
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec(`UPDATE absences SET note = '' WHERE user_id = $1;`, userId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UserExists reports whether the user exists and is not deleted.
 func (p *postgresql) UserExists(userId int) bool {
-	query := `SELECT COUNT(*) FROM users WHERE id = $1;`
-	row := p.db.QueryRow(query, userId)
+	query := `SELECT COUNT(*) FROM users WHERE id = $1 AND deleted_at IS NULL AND ` + p.inTenant("organization_id", 2) + `;`
+	row := p.db.QueryRow(query, userId, p.tenant())
 	var count int
 	err := row.Scan(&count)
 	if err != nil {
@@ -88,115 +320,115 @@ func (p *postgresql) UserExists(userId int) bool {
 
 }
 
-func (p *postgresql) GetUsersInfo(query map[string][]string) ([]model.User, error) {
-	page := 1
-	if val, ok := query["page"]; ok {
-		num, err := strconv.Atoi(val[0])
-		if err == nil {
-			page = num
-		}
-
+// GetUsersInfo returns one page of users. With a cursor the page starts right
+// after the row the cursor was issued for, otherwise at page.Offset.
+func (p *postgresql) GetUsersInfo(query map[string][]string, page pagination.Page) ([]model.User, pagination.Result, error) {
+	users := []model.User{}
+	var result pagination.Result
+	f := newFilter()
+	f.blindIndex = p.keys.BlindIndexes
+	f.add("deleted_at IS NULL")
+	p.scope(f)
+	if err := f.apply(query, userFilterColumns); err != nil {
+		return users, result, err
 	}
-	limit := 10
-	if val, ok := query["limit"]; ok {
-		num, err := strconv.Atoi(val[0])
-		if err == nil {
-			limit = num
-		}
-
+	keys, err := parseSort(query, userFilterColumns)
+	if err != nil {
+		return users, result, err
 	}
-	offset := limit * (page - 1)
-	if val, ok := query["offset"]; ok {
-		num, err := strconv.Atoi(val[0])
-		if err == nil {
-			offset = num
+	if page.Total {
+		total, err := p.count(`SELECT count(*) FROM users`+f.where(), f.args...)
+		if err != nil {
+			return users, result, err
 		}
+		result.Total = &total
 	}
-	sqlQuery, params := generateFilter(query, limit, offset)
-	var rows *sql.Rows
-	var err error
-	users := []model.User{}
-	if len(params) <= 0 {
-		rows, err = p.db.Query(`SELECT * FROM users LIMIT $1 OFFSET $2`, limit, offset)
-	} else {
-		rows, err = p.db.Query(sqlQuery, params...)
+	offset := page.Offset
+	if page.Cursor != "" {
+		if err := f.after(keys, page.Cursor); err != nil {
+			return users, result, err
+		}
+		offset = 0
 	}
+	sqlQuery := `SELECT ` + userColumns + ` FROM users` + f.where() + orderBy(keys)
+	sqlQuery += fmt.Sprintf(" LIMIT %s OFFSET %s;", f.arg(page.Limit+1), f.arg(offset))
+	rows, err := p.db.Query(sqlQuery, f.args...)
 	if err != nil {
-		logrus.Fatalln(err)
-		return users, err
+		return users, result, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		user := model.User{}
-		patronymic := sql.NullString{}
-		err := rows.Scan(&user.Id, &user.PassportNumber, &user.Name, &user.Surname, &patronymic, &user.Address)
-		user.Patronymic = patronymic.String
+		user, err := p.scanUser(rows)
 		if err != nil {
 			logrus.Debug(err)
 			continue
 		}
 		users = append(users, user)
 	}
-	return users, nil
+	if len(users) > page.Limit {
+		users = users[:page.Limit]
+		result.NextCursor = cursorAfter(keys, userSortValue(users[len(users)-1]))
+	}
+	return users, result, rows.Err()
 }
 
-func generateFilter(query map[string][]string, limit, offset int) (string, []any) {
-	sqlQuery := `SELECT * FROM users WHERE`
-	arr := []any{}
-
-	if val, ok := query["id"]; ok {
-		sqlQuery += fmt.Sprintf(" id = $%d,", len(arr)+1)
-		arr = append(arr, val[0])
-	}
-	if val, ok := query["passportNumber"]; ok {
-		sqlQuery += fmt.Sprintf(" passport_number = $%d,", len(arr)+1)
-		arr = append(arr, val[0])
-	}
-	if val, ok := query["name"]; ok {
-		sqlQuery += fmt.Sprintf(" name = $%d,", len(arr)+1)
-		arr = append(arr, val[0])
-	}
-	if val, ok := query["surname"]; ok {
-		sqlQuery += fmt.Sprintf(" surname = $%d,", len(arr)+1)
-		arr = append(arr, val[0])
-	}
-	if val, ok := query["patronymic"]; ok {
-		sqlQuery += fmt.Sprintf(" patronymic = $%d,", len(arr)+1)
-		arr = append(arr, val[0])
-	}
-	if val, ok := query["address"]; ok {
-		sqlQuery += fmt.Sprintf(" address = $%d,", len(arr)+1)
-		arr = append(arr, val[0])
-	}
-	sqlQuery = sqlQuery[:len(sqlQuery)-1]
-	sqlQuery += fmt.Sprintf(" LIMIT $%d OFFSET $%d;", len(arr)+1, len(arr)+2)
-	arr = append(arr, limit, offset)
-	return sqlQuery, arr
+func (p *postgresql) count(query string, args ...any) (int, error) {
+	var total int
+	err := p.db.QueryRow(query, args...).Scan(&total)
+	return total, err
 }
 
 func (p *postgresql) StartNewTask(userId int, name string) (model.Task, error) {
-	query := `INSERT INTO tasks (owner, name) VALUES ($1, $2) returning id, created_at, updated_at, active, duration;`
+	query := `INSERT INTO tasks (owner, name, organization_id)
+		SELECT id, $2, organization_id FROM users WHERE id = $1 AND ` + p.inTenant("organization_id", 3) + `
+		returning id, created_at, updated_at, active, duration;`
+	sessionQuery := `INSERT INTO task_sessions (task_id, started_at) VALUES ($1, $2);`
 	task := model.Task{}
-	err := p.db.QueryRow(query, userId, name).Scan(&task.Id, &task.CreatedAt, &task.UpdatedAt, &task.IsActive, &task.Duration)
-
+	tx, err := p.db.Begin()
 	if err != nil {
-		logrus.Fatalln(err)
 		return task, err
 	}
-	return task, nil
+	defer tx.Rollback()
+	err = tx.QueryRow(query, userId, name, p.tenant()).Scan(&task.Id, &task.CreatedAt, &task.UpdatedAt, &task.IsActive, &task.Duration)
+	if err != nil {
+		logrus.Debug(err)
+		return task, err
+	}
+	_, err = tx.Exec(sessionQuery, task.Id, task.UpdatedAt)
+	if err != nil {
+		logrus.Debug(err)
+		return task, err
+	}
+	return task, tx.Commit()
 }
 
 func (p *postgresql) StartExistingTask(taskId int) error {
-	query := `UPDATE tasks SET active = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1;`
-
-	_, err := p.db.Exec(query, taskId)
-
-	return err
+	query := `UPDATE tasks SET active = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND NOT active AND ` + p.inTenant("organization_id", 2) + ` returning updated_at;`
+	sessionQuery := `INSERT INTO task_sessions (task_id, started_at) VALUES ($1, $2);`
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var startedAt time.Time
+	err = tx.QueryRow(query, taskId, p.tenant()).Scan(&startedAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(sessionQuery, taskId, startedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (p *postgresql) TaskExists(taskId int) bool {
-	query := `SELECT COUNT(*) FROM tasks WHERE id = $1;`
-	row := p.db.QueryRow(query, taskId)
+	query := `SELECT COUNT(*) FROM tasks t JOIN users u ON u.id = t.owner WHERE t.id = $1 AND u.deleted_at IS NULL AND ` + p.inTenant("t.organization_id", 2) + `;`
+	row := p.db.QueryRow(query, taskId, p.tenant())
 	var count int
 	err := row.Scan(&count)
 	if err != nil {
@@ -207,22 +439,38 @@ func (p *postgresql) TaskExists(taskId int) bool {
 
 }
 func (p *postgresql) GetTask(taskId int) (model.Task, error) {
-	query := `SELECT * FROM tasks WHERE id = $1;`
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = $1 AND ` + p.inTenant("organization_id", 2) + `;`
 	task := model.Task{}
-	row := p.db.QueryRow(query, taskId)
+	row := p.db.QueryRow(query, taskId, p.tenant())
 	err := row.Scan(&task.Id, &task.Owner.Id, &task.Name, &task.CreatedAt, &task.UpdatedAt, &task.IsActive, &task.Duration)
+	return task, err
+}
 
+// GetTasksByUser returns all of the user's tasks in creation order, without
+// bringing the duration of active ones up to date.
+func (p *postgresql) GetTasksByUser(userId int) ([]model.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE owner = $1 AND ` + p.inTenant("organization_id", 2) + ` ORDER BY created_at, id;`
+	tasks := []model.Task{}
+	rows, err := p.db.Query(query, userId, p.tenant())
 	if err != nil {
-		logrus.Fatalln(err)
-		return task, err
+		return tasks, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		task := model.Task{}
+		err := rows.Scan(&task.Id, &task.Owner.Id, &task.Name, &task.CreatedAt, &task.UpdatedAt, &task.IsActive, &task.Duration)
+		if err != nil {
+			return tasks, err
+		}
+		tasks = append(tasks, task)
 	}
-	return task, nil
+	return tasks, rows.Err()
 }
 
 func (p *postgresql) IsActiveTask(taskId int) bool {
-	query := `SELECT active FROM tasks WHERE id = $1;`
+	query := `SELECT active FROM tasks WHERE id = $1 AND ` + p.inTenant("organization_id", 2) + `;`
 
-	row := p.db.QueryRow(query, taskId)
+	row := p.db.QueryRow(query, taskId, p.tenant())
 	var active bool
 	err := row.Scan(&active)
 	if err != nil {
@@ -234,47 +482,92 @@ func (p *postgresql) IsActiveTask(taskId int) bool {
 }
 
 func (p *postgresql) StopTask(taskId int) (model.Task, error) {
-	query := `UPDATE tasks SET duration = duration + EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - updated_at)), active = FALSE, updated_at = CURRENT_TIMESTAMP WHERE id = $1;`
-	_, err := p.db.Exec(query, taskId)
+	query := `UPDATE tasks SET duration = duration + EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - updated_at)), active = FALSE, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ` + p.inTenant("organization_id", 2) + `;`
+	sessionQuery := `UPDATE task_sessions SET stopped_at = CURRENT_TIMESTAMP WHERE task_id = $1 AND stopped_at IS NULL;`
 	task := model.Task{}
+	tx, err := p.db.Begin()
+	if err != nil {
+		return task, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(query, taskId, p.tenant())
+	if err != nil {
+		return task, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return task, err
+	}
+	if affected == 0 {
+		return task, sql.ErrNoRows
+	}
+	_, err = tx.Exec(sessionQuery, taskId)
+	if err != nil {
+		return task, err
+	}
+	err = tx.Commit()
 	if err != nil {
 		return task, err
 	}
 	return p.GetTask(taskId)
 }
 
-func (p *postgresql) GetSortedTaskByUser(userId int, query map[string][]string) ([]model.Task, error) {
-	SQLQuery := `SELECT * FROM tasks WHERE owner = $1`
-	args := []any{userId}
+// GetSortedTaskByUser returns one page of the user's tasks, longest first.
+// Durations of active tasks are brought up to date before reading, so a
+// running task can move between pages while they are being fetched.
+func (p *postgresql) GetSortedTaskByUser(userId int, query map[string][]string, page pagination.Page) ([]model.Task, pagination.Result, error) {
+	tasks := []model.Task{}
+	var result pagination.Result
+	f := newFilter(userId)
+	p.scope(f)
+	if err := f.apply(query, taskFilterColumns); err != nil {
+		return tasks, result, err
+	}
 	if val, ok := query["dateFrom"]; ok {
 		_, err := time.Parse(time.RFC3339, val[0])
 		if err == nil {
-			SQLQuery += fmt.Sprintf(" AND updated_at > $%d", len(args)+1)
-			args = append(args, val[0])
+			f.add("updated_at > " + f.arg(val[0]))
 		}
 	}
 	if val, ok := query["dateTo"]; ok {
 		_, err := time.Parse(time.RFC3339, val[0])
 		if err == nil {
-			SQLQuery += fmt.Sprintf(" AND updated_at < $%d", len(args)+1)
-			args = append(args, val[0])
+			f.add("updated_at < " + f.arg(val[0]))
 		}
 	}
-	tasks := []model.Task{}
 
-	updateDurationQuery := `UPDATE tasks SET duration = duration + EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - updated_at)), updated_at = CURRENT_TIMESTAMP WHERE owner = $1;`
-	_, err := p.db.Exec(updateDurationQuery, userId)
+	updateDurationQuery := `UPDATE tasks SET duration = duration + EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - updated_at)), updated_at = CURRENT_TIMESTAMP
+		WHERE owner = $1 AND active AND ` + p.inTenant("organization_id", 2) + `;`
+	_, err := p.db.Exec(updateDurationQuery, userId, p.tenant())
 	if err != nil {
-		logrus.Fatalln(err)
-		return tasks, err
+		return tasks, result, err
 	}
 
-	SQLQuery += " ORDER BY duration DESC"
-	rows, err := p.db.Query(SQLQuery, args...)
+	if page.Total {
+		total, err := p.count(`SELECT count(*) FROM tasks WHERE owner = $1`+f.and(), f.args...)
+		if err != nil {
+			return tasks, result, err
+		}
+		result.Total = &total
+	}
+	offset := page.Offset
+	if page.Cursor != "" {
+		if err := f.after(taskSortKeys, page.Cursor); err != nil {
+			return tasks, result, err
+		}
+		offset = 0
+	}
+	SQLQuery := `SELECT ` + taskColumns + ` FROM tasks WHERE owner = $1` + f.and() + orderBy(taskSortKeys)
+	if page.Limit > 0 {
+		SQLQuery += fmt.Sprintf(" LIMIT %s", f.arg(page.Limit+1))
+	}
+	SQLQuery += fmt.Sprintf(" OFFSET %s", f.arg(offset))
+	rows, err := p.db.Query(SQLQuery, f.args...)
 	if err != nil {
-		logrus.Fatalln(err)
-		return tasks, err
+		return tasks, result, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		task := model.Task{}
@@ -285,5 +578,9 @@ func (p *postgresql) GetSortedTaskByUser(userId int, query map[string][]string)
 		}
 		tasks = append(tasks, task)
 	}
-	return tasks, err
+	if page.Limit > 0 && len(tasks) > page.Limit {
+		tasks = tasks[:page.Limit]
+		result.NextCursor = cursorAfter(taskSortKeys, taskSortValue(tasks[len(tasks)-1]))
+	}
+	return tasks, result, rows.Err()
 }
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+func (p *postgresql) ScheduleExists(userId int) bool {
+	query := `SELECT COUNT(*) FROM schedules s JOIN users u ON u.id = s.user_id WHERE s.user_id = $1 AND ` + p.inTenant("u.organization_id", 2) + `;`
+	row := p.db.QueryRow(query, userId, p.tenant())
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		logrus.Debug(err)
+		return false
+	}
+	return count > 0
+}
+
+func (p *postgresql) GetSchedule(userId int) (model.Schedule, error) {
+	query := `SELECT s.user_id, s.weekly_hours, s.work_days, to_char(s.day_start, 'HH24:MI'), to_char(s.day_end, 'HH24:MI'), s.timezone, s.region
+		FROM schedules s JOIN users u ON u.id = s.user_id WHERE s.user_id = $1 AND ` + p.inTenant("u.organization_id", 2) + `;`
+	schedule := model.Schedule{}
+	workDays := []int64{}
+	err := p.db.QueryRow(query, userId, p.tenant()).Scan(&schedule.UserId, &schedule.WeeklyHours, pq.Array(&workDays), &schedule.DayStart, &schedule.DayEnd, &schedule.Timezone, &schedule.Region)
+	if err != nil {
+		return schedule, err
+	}
+	schedule.WorkDays = make([]int, 0, len(workDays))
+	for _, day := range workDays {
+		schedule.WorkDays = append(schedule.WorkDays, int(day))
+	}
+	return schedule, nil
+}
+
+func (p *postgresql) SaveSchedule(schedule model.Schedule) error {
+	query := `INSERT INTO schedules (user_id, weekly_hours, work_days, day_start, day_end, timezone, region)
+		SELECT $1::int, $2::int, $3::int[], $4::time, $5::time, $6, $7 FROM users WHERE id = $1 AND ` + p.inTenant("organization_id", 8) + `
+		ON CONFLICT (user_id) DO UPDATE SET weekly_hours = EXCLUDED.weekly_hours, work_days = EXCLUDED.work_days,
+		day_start = EXCLUDED.day_start, day_end = EXCLUDED.day_end, timezone = EXCLUDED.timezone, region = EXCLUDED.region;`
+	workDays := make([]int64, 0, len(schedule.WorkDays))
+	for _, day := range schedule.WorkDays {
+		workDays = append(workDays, int64(day))
+	}
+	_, err := p.db.Exec(query, schedule.UserId, schedule.WeeklyHours, pq.Array(workDays), schedule.DayStart, schedule.DayEnd, schedule.Timezone, schedule.Region, p.tenant())
+	return err
+}
+
+// GetSessionsByUser returns the user's sessions overlapping [from, to).
+func (p *postgresql) GetSessionsByUser(userId int, from, to time.Time) ([]model.Session, error) {
+	query := `SELECT s.id, s.task_id, s.started_at, s.stopped_at FROM task_sessions s JOIN tasks t ON t.id = s.task_id
+		WHERE t.owner = $1 AND s.started_at < $3 AND (s.stopped_at IS NULL OR s.stopped_at > $2) AND ` + p.inTenant("t.organization_id", 4) + `
+		ORDER BY s.started_at;`
+	sessions := []model.Session{}
+	rows, err := p.db.Query(query, userId, from.UTC(), to.UTC(), p.tenant())
+	if err != nil {
+		return sessions, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		session := model.Session{}
+		err := rows.Scan(&session.Id, &session.TaskId, &session.StartedAt, &session.StoppedAt)
+		if err != nil {
+			logrus.Debug(err)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (p *postgresql) GetSessionsByTasks(taskIds []int) ([]model.Session, error) {
+	query := `SELECT s.id, s.task_id, s.started_at, s.stopped_at FROM task_sessions s JOIN tasks t ON t.id = s.task_id
+		WHERE s.task_id = ANY($1) AND ` + p.inTenant("t.organization_id", 2) + ` ORDER BY s.started_at;`
+	sessions := []model.Session{}
+	ids := make([]int64, 0, len(taskIds))
+	for _, id := range taskIds {
+		ids = append(ids, int64(id))
+	}
+	rows, err := p.db.Query(query, pq.Array(ids), p.tenant())
+	if err != nil {
+		return sessions, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		session := model.Session{}
+		err := rows.Scan(&session.Id, &session.TaskId, &session.StartedAt, &session.StoppedAt)
+		if err != nil {
+			logrus.Debug(err)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
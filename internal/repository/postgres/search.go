@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/lib/pq"
+)
+
+// Search matches users and tasks by full text, stemmed with the Russian and
+// English dictionaries, and by trigram word similarity so that misspelled
+// names are still found. Hits are ranked by the sum of both scores. Deleted
+// users and their tasks are left out, as is anything outside the tenant.
+func (p *postgresql) Search(text string, types []string, limit int) ([]model.SearchHit, error) {
+	query := `WITH q AS (
+		SELECT websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1) AS query
+	)
+	SELECT type, id, user_id, title, highlight, score FROM (
+		SELECT 'user' AS type, u.id, u.id AS user_id,
+			concat_ws(' ', u.surname, u.name, u.patronymic) AS title,
+			ts_headline('russian', u.search_text, q.query, 'StartSel=<mark>, StopSel=</mark>') AS highlight,
+			ts_rank(u.search, q.query) + word_similarity($1, u.search_text) AS score
+		FROM users u, q
+		WHERE 'user' = ANY($2) AND u.deleted_at IS NULL AND ` + p.inTenant("u.organization_id", 4) + `
+			AND (u.search @@ q.query OR $1 <% u.search_text)
+		UNION ALL
+		SELECT 'task', t.id, t.owner,
+			coalesce(t.name, ''),
+			ts_headline('russian', coalesce(t.name, ''), q.query, 'StartSel=<mark>, StopSel=</mark>'),
+			ts_rank(t.search, q.query) + word_similarity($1, coalesce(t.name, ''))
+		FROM tasks t JOIN users u ON u.id = t.owner, q
+		WHERE 'task' = ANY($2) AND u.deleted_at IS NULL AND ` + p.inTenant("u.organization_id", 4) + `
+			AND (t.search @@ q.query OR $1 <% t.name)
+	) hits
+	ORDER BY score DESC, type, id
+	LIMIT $3;`
+	hits := []model.SearchHit{}
+	rows, err := p.db.Query(query, text, pq.Array(types), limit, p.tenant())
+	if err != nil {
+		return hits, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		hit := model.SearchHit{}
+		err := rows.Scan(&hit.Type, &hit.Id, &hit.UserId, &hit.Title, &hit.Highlight, &hit.Score)
+		if err != nil {
+			return hits, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+func (p *postgresql) GetSession(sessionId int) (model.Session, bool, error) {
+	query := `SELECT s.id, s.task_id, s.started_at, s.stopped_at FROM task_sessions s JOIN tasks t ON t.id = s.task_id
+		WHERE s.id = $1 AND ` + p.inTenant("t.organization_id", 2) + `;`
+	session := model.Session{}
+	err := p.db.QueryRow(query, sessionId, p.tenant()).Scan(&session.Id, &session.TaskId, &session.StartedAt, &session.StoppedAt)
+	if err == sql.ErrNoRows {
+		return session, false, nil
+	}
+	return session, err == nil, err
+}
+
+// SessionOverlaps reports whether the session would overlap another session
+// of its task. Running sessions last until now.
+func (p *postgresql) SessionOverlaps(session model.Session) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM task_sessions
+		WHERE task_id = $1 AND id <> $2
+		AND started_at < COALESCE($4, CURRENT_TIMESTAMP) AND COALESCE(stopped_at, CURRENT_TIMESTAMP) > $3);`
+	var overlaps bool
+	err := p.db.QueryRow(query, session.TaskId, session.Id, session.StartedAt, session.StoppedAt).Scan(&overlaps)
+	return overlaps, err
+}
+
+// UpdateSession moves the start and stop of a session and keeps the task
+// consistent: the duration of a stopped session is added to or taken from
+// the task, a running session moves the start of the task's current run.
+func (p *postgresql) UpdateSession(session model.Session) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	old := model.Session{}
+	query := `SELECT s.started_at, s.stopped_at FROM task_sessions s JOIN tasks t ON t.id = s.task_id
+		WHERE s.id = $1 AND ` + p.inTenant("t.organization_id", 2) + ` FOR UPDATE OF s;`
+	if err := tx.QueryRow(query, session.Id, p.tenant()).Scan(&old.StartedAt, &old.StoppedAt); err != nil {
+		return err
+	}
+	query = `UPDATE task_sessions SET started_at = $2, stopped_at = $3 WHERE id = $1;`
+	if _, err := tx.Exec(query, session.Id, session.StartedAt, session.StoppedAt); err != nil {
+		return err
+	}
+	if session.StoppedAt == nil {
+		query = `UPDATE tasks SET updated_at = $2 WHERE id = $1 AND active;`
+		_, err = tx.Exec(query, session.TaskId, session.StartedAt)
+	} else {
+		delta := sessionLength(session) - sessionLength(old)
+		query = `UPDATE tasks SET duration = GREATEST(duration + $2, 0) WHERE id = $1;`
+		_, err = tx.Exec(query, session.TaskId, int(delta.Seconds()))
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func sessionLength(session model.Session) time.Duration {
+	if session.StoppedAt == nil {
+		return 0
+	}
+	return session.StoppedAt.Sub(session.StartedAt)
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/pagination"
+	"github.com/TimeTracker-Effective-Mobile/internal/validation"
+)
+
+type sortKey struct {
+	field string
+	col   column
+	desc  bool
+}
+
+var taskSortKeys = []sortKey{
+	{field: "duration", col: column{name: "duration", kind: intColumn}, desc: true},
+	{field: "id", col: column{name: "id", kind: intColumn}},
+}
+
+// parseSort reads a sort parameter such as "surname,-id", where a leading "-"
+// sorts in descending order. Only whitelisted fields are accepted and id is
+// always appended as the final key, so rows with equal sort keys come back in
+// a stable order across pages.
+func parseSort(query map[string][]string, columns filterColumns) ([]sortKey, error) {
+	keys := []sortKey{}
+	seen := map[string]bool{}
+	for _, value := range query["sort"] {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			desc := strings.HasPrefix(field, "-")
+			field = strings.TrimLeft(field, "+-")
+			col, ok := columns[field]
+			if !ok {
+				return nil, &validation.Error{
+					Message: "invalid sort",
+					Fields:  map[string]string{"sort": fmt.Sprintf("unknown field %q", field)},
+				}
+			}
+			if col.kind == blindIndexColumn || col.kind == encryptedColumn {
+				return nil, &validation.Error{
+					Message: "invalid sort",
+					Fields:  map[string]string{"sort": fmt.Sprintf("can't sort on encrypted field %q", field)},
+				}
+			}
+			if seen[col.name] {
+				continue
+			}
+			seen[col.name] = true
+			keys = append(keys, sortKey{field: field, col: col, desc: desc})
+		}
+	}
+	if !seen["id"] {
+		keys = append(keys, sortKey{field: "id", col: column{name: "id", kind: intColumn}})
+	}
+	return keys, nil
+}
+
+func orderBy(keys []sortKey) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		direction := "ASC"
+		if key.desc {
+			direction = "DESC"
+		}
+		parts = append(parts, key.col.expr()+" "+direction)
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// sortSpec is the canonical form of keys stored in cursors.
+func sortSpec(keys []sortKey) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.desc {
+			parts = append(parts, "-"+key.field)
+		} else {
+			parts = append(parts, key.field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// after restricts the query to rows that sort strictly after the cursor:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with < for descending keys.
+func (f *filter) after(keys []sortKey, value string) error {
+	invalid := func(reason string) error {
+		return &validation.Error{Message: pagination.ErrInvalidCursor.Error(), Fields: map[string]string{"cursor": reason}}
+	}
+	cursor, err := pagination.Decode(value)
+	if err != nil {
+		return invalid("malformed cursor")
+	}
+	if cursor.Sort != sortSpec(keys) || len(cursor.Values) != len(keys) {
+		return invalid("cursor was issued for a different sort order")
+	}
+	placeholders := make([]string, len(keys))
+	for i, key := range keys {
+		value, err := key.col.parse(cursor.Values[i])
+		if err != nil {
+			return invalid("malformed cursor")
+		}
+		placeholders[i] = f.arg(value)
+	}
+	alternatives := make([]string, 0, len(keys))
+	for i, key := range keys {
+		conditions := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, keys[j].col.expr()+" = "+placeholders[j])
+		}
+		op := " > "
+		if key.desc {
+			op = " < "
+		}
+		conditions = append(conditions, key.col.expr()+op+placeholders[i])
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+	f.add("(" + strings.Join(alternatives, " OR ") + ")")
+	return nil
+}
+
+// cursorAfter encodes the position of the last row of a page.
+func cursorAfter(keys []sortKey, value func(field string) string) string {
+	cursor := pagination.Cursor{Sort: sortSpec(keys), Values: make([]string, len(keys))}
+	for i, key := range keys {
+		cursor.Values[i] = value(key.field)
+	}
+	return cursor.Encode()
+}
+
+func userSortValue(user model.User) func(field string) string {
+	return func(field string) string {
+		switch field {
+		case "id":
+			return strconv.Itoa(user.Id)
+		case "name":
+			return user.Name
+		case "surname":
+			return user.Surname
+		case "patronymic":
+			return user.Patronymic
+		case "timezone":
+			return user.Timezone
+		case "status":
+			return user.Status
+		case "team_id":
+			if user.TeamId == nil {
+				return "0"
+			}
+			return strconv.Itoa(*user.TeamId)
+		}
+		return ""
+	}
+}
+
+func taskSortValue(task model.Task) func(field string) string {
+	return func(field string) string {
+		if field == "duration" {
+			return strconv.Itoa(task.Duration)
+		}
+		return strconv.Itoa(task.Id)
+	}
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+// SaveTeam creates the team in the organization and reports false if the
+// name is taken there.
+func (p *postgresql) SaveTeam(team *model.Team) (bool, error) {
+	query := `INSERT INTO teams (name, organization_id) VALUES ($1, $2) ON CONFLICT (organization_id, name) DO NOTHING RETURNING id;`
+	err := p.db.QueryRow(query, team.Name, p.tenant()).Scan(&team.Id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (p *postgresql) GetTeams() ([]model.Team, error) {
+	query := `SELECT id, name FROM teams WHERE ` + p.inTenant("organization_id", 1) + ` ORDER BY name;`
+	teams := []model.Team{}
+	rows, err := p.db.Query(query, p.tenant())
+	if err != nil {
+		return teams, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		team := model.Team{}
+		if err := rows.Scan(&team.Id, &team.Name); err != nil {
+			return teams, err
+		}
+		teams = append(teams, team)
+	}
+	return teams, rows.Err()
+}
+
+func (p *postgresql) TeamExists(teamId int) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM teams WHERE id = $1 AND ` + p.inTenant("organization_id", 2) + `);`
+	var exists bool
+	err := p.db.QueryRow(query, teamId, p.tenant()).Scan(&exists)
+	return exists, err
+}
+
+// SetUserTeam moves a user that is not deleted to the team, or out of any
+// team for nil.
+func (p *postgresql) SetUserTeam(userId int, teamId *int) (bool, error) {
+	query := `UPDATE users SET team_id = $2 WHERE id = $1 AND deleted_at IS NULL AND ` + p.inTenant("organization_id", 3) + `;`
+	res, err := p.db.Exec(query, userId, teamId, p.tenant())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+func TestInTenant(t *testing.T) {
+	base := &postgresql{}
+	tests := []struct {
+		name    string
+		storage *postgresql
+		want    string
+		tenant  any
+	}{
+		{"not scoped", base, "organization_id = $2", 0},
+		{"organization 0", base.WithTenant(0), "organization_id = $2", 0},
+		{"scoped", base.WithTenant(3), "organization_id = $2", 3},
+		{"scoped after unscoped", base.Unscoped().WithTenant(3), "organization_id = $2", 3},
+		{"unscoped", base.WithTenant(3).Unscoped(), "$2::int IS NULL", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.storage.inTenant("organization_id", 2); got != tt.want {
+				t.Errorf("inTenant() = %q, want %q", got, tt.want)
+			}
+			if got := tt.storage.tenant(); got != tt.tenant {
+				t.Errorf("tenant() = %v, want %v", got, tt.tenant)
+			}
+			f := newFilter()
+			tt.storage.scope(f)
+			if scoped := len(f.conditions) == 1; scoped != (tt.tenant != nil) {
+				t.Errorf("scope() conditions = %v", f.conditions)
+			}
+		})
+	}
+}
+
+func TestTenantScope(t *testing.T) {
+	storage := openTestStorage(t)
+	org := testOrganization(t, storage)
+	user := model.User{PassportNumber: testPassport(), Timezone: "UTC", Status: model.UserActive}
+	if err := storage.WithTenant(org).SaveUser(&user); err != nil {
+		t.Fatal(err)
+	}
+
+	if !storage.WithTenant(org).UserExists(user.Id) {
+		t.Error("user not found in its organization")
+	}
+	if storage.WithTenant(testOrganization(t, storage)).UserExists(user.Id) {
+		t.Error("user found in another organization")
+	}
+	for name, scoped := range map[string]*postgresql{"not scoped": storage, "organization 0": storage.WithTenant(0)} {
+		if scoped.UserExists(user.Id) {
+			t.Errorf("%s: user found", name)
+		}
+		if _, _, err := scoped.GetUserByPassport(user.PassportNumber); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if err := scoped.SaveUser(&model.User{PassportNumber: testPassport(), Timezone: "UTC", Status: model.UserActive}); err == nil {
+			t.Errorf("%s: user saved outside of any organization", name)
+		}
+	}
+	if !storage.Unscoped().UserExists(user.Id) {
+		t.Error("unscoped storage does not find the user")
+	}
+	if err := storage.Unscoped().SaveUser(&model.User{PassportNumber: testPassport(), Timezone: "UTC", Status: model.UserActive}); err == nil {
+		t.Error("unscoped storage saved a user outside of any organization")
+	}
+}
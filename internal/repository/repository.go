@@ -1,13 +1,17 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/pagination"
 	"github.com/TimeTracker-Effective-Mobile/internal/repository/postgres"
+	"github.com/sirupsen/logrus"
 )
 
 type dbStorage interface {
-	GetUsersInfo(query map[string][]string) ([]model.User, error)
-	GetSortedTaskByUser(userId int, query map[string][]string) ([]model.Task, error)
+	GetUsersInfo(query map[string][]string, page pagination.Page) ([]model.User, pagination.Result, error)
+	GetSortedTaskByUser(userId int, query map[string][]string, page pagination.Page) ([]model.Task, pagination.Result, error)
 	StartNewTask(userId int, name string) (model.Task, error)
 	StartExistingTask(taskId int) error
 	TaskExists(taskId int) bool
@@ -15,24 +19,108 @@ type dbStorage interface {
 	IsActiveTask(taskId int) bool
 	StopTask(taskId int) (model.Task, error)
 	DeleteUser(userId int) error
+	RestoreUser(userId int) (bool, error)
+	PurgeUser(userId int) (bool, error)
+	AnonymizeUser(userId int) error
+	GetTasksByUser(userId int) ([]model.Task, error)
 	UpdateUser(user model.User) error
 	SaveUser(user *model.User) error
+	GetUser(userId int) (model.User, error)
+	GetUserByPassport(passportNumber string) (model.User, bool, error)
+	GetUsersByStatus(status string) ([]model.User, error)
+	EnrichUser(user model.User) (bool, error)
+	FailPendingUser(userId int) (bool, error)
+	ScheduleExists(userId int) bool
+	GetSchedule(userId int) (model.Schedule, error)
+	SaveSchedule(schedule model.Schedule) error
+	GetSessionsByUser(userId int, from, to time.Time) ([]model.Session, error)
+	GetSessionsByTasks(taskIds []int) ([]model.Session, error)
+	SaveAbsence(absence *model.Absence) error
+	UpdateAbsence(absence model.Absence) error
+	DeleteAbsence(absenceId int) error
+	AbsenceExists(absenceId int) bool
+	GetAbsence(absenceId int) (model.Absence, error)
+	GetAbsences(query map[string][]string) ([]model.Absence, error)
+	GetAbsencesForUser(userId int, region string, from, to time.Time) ([]model.Absence, error)
+	GetPeopleCacheEntry(passportNumber string) (model.PeopleCacheEntry, bool, error)
+	SavePeopleCacheEntry(entry model.PeopleCacheEntry) error
+	DeletePeopleCacheEntry(passportNumber string) error
+	Search(text string, types []string, limit int) ([]model.SearchHit, error)
+	GetTask(taskId int) (model.Task, error)
+	GetCredential(username string) (model.Credential, bool, error)
+	SaveCredential(credential *model.Credential) error
+	GetCredentialById(id int) (model.Credential, bool, error)
+	SaveRefreshToken(credentialId int, hash string, expiresAt time.Time) error
+	GetRefreshToken(hash string) (model.RefreshToken, bool, error)
+	RevokeRefreshToken(hash string) (int, bool, error)
+	LogOutRefreshToken(hash string) error
+	RevokeRefreshTokens(credentialId int) error
+	SaveAPIKey(key *model.APIKey, hash string) error
+	GetAPIKeys(credentialId int) ([]model.APIKey, error)
+	UseAPIKey(hash string) (model.APIKey, bool, error)
+	DeleteAPIKey(id, credentialId, organizationId int) (bool, error)
+	SaveTeam(team *model.Team) (bool, error)
+	GetTeams() ([]model.Team, error)
+	TeamExists(teamId int) (bool, error)
+	SetUserTeam(userId int, teamId *int) (bool, error)
+	GetSession(sessionId int) (model.Session, bool, error)
+	SessionOverlaps(session model.Session) (bool, error)
+	UpdateSession(session model.Session) error
+	SaveOrganization(organization *model.Organization, admin *model.Credential) (bool, error)
+	GetOrganizations() ([]model.Organization, error)
+	UserInOrganization(userId, organizationId int) bool
+	SaveAuditRecord(record *model.AuditRecord) error
+	GetAuditRecords(userId int) ([]model.AuditRecord, error)
 }
 
 type repository struct {
-	db dbStorage
+	db       dbStorage
+	tenant   func(orgId int) dbStorage
+	unscoped func() dbStorage
 }
 
+// New returns the repository scoped to no organization: it finds no users
+// or tasks until narrowed by WithTenant or widened by Unscoped.
 func New() *repository {
-	return &repository{db: postgres.New()}
+	r, err := Open(postgres.ConnStringFromEnv(), "file://migrations")
+	if err != nil {
+		logrus.Fatalf(err.Error())
+	}
+	return r
+}
+
+// Open is New for the database at connStr with the migrations found at the
+// migrations source URL.
+func Open(connStr, migrations string) (*repository, error) {
+	db, err := postgres.Open(connStr, migrations)
+	if err != nil {
+		return nil, err
+	}
+	return &repository{
+		db:       db,
+		tenant:   func(orgId int) dbStorage { return db.WithTenant(orgId) },
+		unscoped: func() dbStorage { return db.Unscoped() },
+	}, nil
+}
+
+// WithTenant returns the repository limited to the users and tasks of an
+// organization.
+func (r *repository) WithTenant(orgId int) *repository {
+	return &repository{db: r.tenant(orgId), tenant: r.tenant, unscoped: r.unscoped}
+}
+
+// Unscoped returns the repository for background jobs, which see the users
+// and tasks of all organizations.
+func (r *repository) Unscoped() *repository {
+	return &repository{db: r.unscoped(), tenant: r.tenant, unscoped: r.unscoped}
 }
 
-func (r *repository) GetUsersInfo(query map[string][]string) ([]model.User, error) {
-	return r.db.GetUsersInfo(query)
+func (r *repository) GetUsersInfo(query map[string][]string, page pagination.Page) ([]model.User, pagination.Result, error) {
+	return r.db.GetUsersInfo(query, page)
 }
 
-func (r *repository) GetSortedTaskByUser(userId int, query map[string][]string) ([]model.Task, error) {
-	return r.db.GetSortedTaskByUser(userId, query)
+func (r *repository) GetSortedTaskByUser(userId int, query map[string][]string, page pagination.Page) ([]model.Task, pagination.Result, error) {
+	return r.db.GetSortedTaskByUser(userId, query, page)
 }
 
 func (r *repository) StartNewTask(userId int, name string) (model.Task, error) {
@@ -63,6 +151,22 @@ func (r *repository) DeleteUser(userId int) error {
 	return r.db.DeleteUser(userId)
 }
 
+func (r *repository) RestoreUser(userId int) (bool, error) {
+	return r.db.RestoreUser(userId)
+}
+
+func (r *repository) PurgeUser(userId int) (bool, error) {
+	return r.db.PurgeUser(userId)
+}
+
+func (r *repository) AnonymizeUser(userId int) error {
+	return r.db.AnonymizeUser(userId)
+}
+
+func (r *repository) GetTasksByUser(userId int) ([]model.Task, error) {
+	return r.db.GetTasksByUser(userId)
+}
+
 func (r *repository) UpdateUser(user model.User) error {
 	return r.db.UpdateUser(user)
 }
@@ -70,3 +174,187 @@ func (r *repository) UpdateUser(user model.User) error {
 func (r *repository) SaveUser(user *model.User) error {
 	return r.db.SaveUser(user)
 }
+
+func (r *repository) GetUser(userId int) (model.User, error) {
+	return r.db.GetUser(userId)
+}
+
+func (r *repository) GetUserByPassport(passportNumber string) (model.User, bool, error) {
+	return r.db.GetUserByPassport(passportNumber)
+}
+
+func (r *repository) GetUsersByStatus(status string) ([]model.User, error) {
+	return r.db.GetUsersByStatus(status)
+}
+
+func (r *repository) EnrichUser(user model.User) (bool, error) {
+	return r.db.EnrichUser(user)
+}
+
+func (r *repository) FailPendingUser(userId int) (bool, error) {
+	return r.db.FailPendingUser(userId)
+}
+
+func (r *repository) ScheduleExists(userId int) bool {
+	return r.db.ScheduleExists(userId)
+}
+
+func (r *repository) GetSchedule(userId int) (model.Schedule, error) {
+	return r.db.GetSchedule(userId)
+}
+
+func (r *repository) SaveSchedule(schedule model.Schedule) error {
+	return r.db.SaveSchedule(schedule)
+}
+
+func (r *repository) GetSessionsByUser(userId int, from, to time.Time) ([]model.Session, error) {
+	return r.db.GetSessionsByUser(userId, from, to)
+}
+
+func (r *repository) GetSessionsByTasks(taskIds []int) ([]model.Session, error) {
+	return r.db.GetSessionsByTasks(taskIds)
+}
+
+func (r *repository) SaveAbsence(absence *model.Absence) error {
+	return r.db.SaveAbsence(absence)
+}
+
+func (r *repository) UpdateAbsence(absence model.Absence) error {
+	return r.db.UpdateAbsence(absence)
+}
+
+func (r *repository) DeleteAbsence(absenceId int) error {
+	return r.db.DeleteAbsence(absenceId)
+}
+
+func (r *repository) AbsenceExists(absenceId int) bool {
+	return r.db.AbsenceExists(absenceId)
+}
+
+func (r *repository) GetAbsence(absenceId int) (model.Absence, error) {
+	return r.db.GetAbsence(absenceId)
+}
+
+func (r *repository) GetAbsences(query map[string][]string) ([]model.Absence, error) {
+	return r.db.GetAbsences(query)
+}
+
+func (r *repository) GetAbsencesForUser(userId int, region string, from, to time.Time) ([]model.Absence, error) {
+	return r.db.GetAbsencesForUser(userId, region, from, to)
+}
+
+func (r *repository) GetPeopleCacheEntry(passportNumber string) (model.PeopleCacheEntry, bool, error) {
+	return r.db.GetPeopleCacheEntry(passportNumber)
+}
+
+func (r *repository) SavePeopleCacheEntry(entry model.PeopleCacheEntry) error {
+	return r.db.SavePeopleCacheEntry(entry)
+}
+
+func (r *repository) DeletePeopleCacheEntry(passportNumber string) error {
+	return r.db.DeletePeopleCacheEntry(passportNumber)
+}
+
+func (r *repository) Search(text string, types []string, limit int) ([]model.SearchHit, error) {
+	return r.db.Search(text, types, limit)
+}
+
+func (r *repository) GetTask(taskId int) (model.Task, error) {
+	return r.db.GetTask(taskId)
+}
+
+func (r *repository) GetCredential(username string) (model.Credential, bool, error) {
+	return r.db.GetCredential(username)
+}
+
+func (r *repository) SaveCredential(credential *model.Credential) error {
+	return r.db.SaveCredential(credential)
+}
+
+func (r *repository) GetCredentialById(id int) (model.Credential, bool, error) {
+	return r.db.GetCredentialById(id)
+}
+
+func (r *repository) SaveRefreshToken(credentialId int, hash string, expiresAt time.Time) error {
+	return r.db.SaveRefreshToken(credentialId, hash, expiresAt)
+}
+
+func (r *repository) GetRefreshToken(hash string) (model.RefreshToken, bool, error) {
+	return r.db.GetRefreshToken(hash)
+}
+
+func (r *repository) RevokeRefreshToken(hash string) (int, bool, error) {
+	return r.db.RevokeRefreshToken(hash)
+}
+
+func (r *repository) LogOutRefreshToken(hash string) error {
+	return r.db.LogOutRefreshToken(hash)
+}
+
+func (r *repository) RevokeRefreshTokens(credentialId int) error {
+	return r.db.RevokeRefreshTokens(credentialId)
+}
+
+func (r *repository) SaveAPIKey(key *model.APIKey, hash string) error {
+	return r.db.SaveAPIKey(key, hash)
+}
+
+func (r *repository) GetAPIKeys(credentialId int) ([]model.APIKey, error) {
+	return r.db.GetAPIKeys(credentialId)
+}
+
+func (r *repository) UseAPIKey(hash string) (model.APIKey, bool, error) {
+	return r.db.UseAPIKey(hash)
+}
+
+func (r *repository) DeleteAPIKey(id, credentialId, organizationId int) (bool, error) {
+	return r.db.DeleteAPIKey(id, credentialId, organizationId)
+}
+
+func (r *repository) SaveTeam(team *model.Team) (bool, error) {
+	return r.db.SaveTeam(team)
+}
+
+func (r *repository) GetTeams() ([]model.Team, error) {
+	return r.db.GetTeams()
+}
+
+func (r *repository) TeamExists(teamId int) (bool, error) {
+	return r.db.TeamExists(teamId)
+}
+
+func (r *repository) SetUserTeam(userId int, teamId *int) (bool, error) {
+	return r.db.SetUserTeam(userId, teamId)
+}
+
+func (r *repository) GetSession(sessionId int) (model.Session, bool, error) {
+	return r.db.GetSession(sessionId)
+}
+
+func (r *repository) SessionOverlaps(session model.Session) (bool, error) {
+	return r.db.SessionOverlaps(session)
+}
+
+func (r *repository) UpdateSession(session model.Session) error {
+	return r.db.UpdateSession(session)
+}
+
+func (r *repository) SaveOrganization(organization *model.Organization, admin *model.Credential) (bool, error) {
+	return r.db.SaveOrganization(organization, admin)
+}
+
+func (r *repository) GetOrganizations() ([]model.Organization, error) {
+	return r.db.GetOrganizations()
+}
+
+func (r *repository) UserInOrganization(userId, organizationId int) bool {
+	return r.db.UserInOrganization(userId, organizationId)
+}
+
+func (r *repository) SaveAuditRecord(record *model.AuditRecord) error {
+	return r.db.SaveAuditRecord(record)
+}
+
+func (r *repository) GetAuditRecords(userId int) ([]model.AuditRecord, error) {
+	return r.db.GetAuditRecords(userId)
+}
@@ -0,0 +1,271 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/period"
+	"github.com/TimeTracker-Effective-Mobile/internal/policy"
+	"github.com/TimeTracker-Effective-Mobile/internal/service/schedule"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// @Summary Get absences
+// @Description Retrieve vacations, sick leaves and public holidays. Managers only see the absences of their team and the holidays.
+// @Accept json
+// @Produce json
+// @Param userId query int false "User ID"
+// @Param teamId query int false "Team ID"
+// @Param region query string false "Region"
+// @Param kind query string false "Kind" Enums(vacation, sick_leave, holiday)
+// @Param dateFrom query string false "Date From (YYYY-MM-DD)"
+// @Param dateTo query string false "Date To (YYYY-MM-DD)"
+// @Success 200 {array} model.Absence "List of absences"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /absences [get]
+func (r *router) getAbsences() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		userId, _ := strconv.Atoi(query.Get("userId"))
+		if !r.authorize(c, policy.ReadAbsences, userId) {
+			return
+		}
+		if err := policy.RestrictAbsenceQuery(principalFrom(c), query); err != nil {
+			c.JSON(http.StatusForbidden, err.Error())
+			return
+		}
+		absences, err := r.scheduleService(c).GetAbsences(query)
+		if errors.Is(err, schedule.ErrInvalidAbsence) {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusOK, absences)
+	}
+}
+
+// @Summary Get absence
+// @Description Retrieve an absence by its ID
+// @Accept json
+// @Produce json
+// @Param absence path int true "Absence ID"
+// @Success 200 {object} model.Absence "Absence"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "absence not exist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /absences/{absence} [get]
+func (r *router) getAbsence() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !r.authorize(c, policy.ReadAbsences, 0) {
+			return
+		}
+		absenceId, err := strconv.Atoi(c.Param("absence"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		absence, err := r.scheduleService(c).GetAbsence(absenceId)
+		if errors.Is(err, schedule.ErrAbsenceNotFound) {
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if absence.UserId != nil && !r.authorize(c, policy.ReadAbsences, *absence.UserId) {
+			return
+		}
+		c.JSON(http.StatusOK, absence)
+	}
+}
+
+// @Summary Create absence
+// @Description Create a vacation or sick leave for a user, or a public holiday for a region
+// @Accept json
+// @Produce json
+// @Param request body model.Absence true "Absence"
+// @Success 201 {object} model.Absence "Created absence"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /absences [post]
+func (r *router) createAbsence() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !r.authorize(c, policy.ManageAbsences, 0) {
+			return
+		}
+		var body model.Absence
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		absence, err := r.scheduleService(c).CreateAbsence(body)
+		if errors.Is(err, schedule.ErrInvalidAbsence) {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusCreated, absence)
+	}
+}
+
+// @Summary Update absence
+// @Description Replace an absence by its ID
+// @Accept json
+// @Produce json
+// @Param absence path int true "Absence ID"
+// @Param request body model.Absence true "Absence"
+// @Success 200 {object} model.Absence "Updated absence"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "absence not exist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /absences/{absence} [put]
+func (r *router) updateAbsence() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !r.authorize(c, policy.ManageAbsences, 0) {
+			return
+		}
+		absenceId, err := strconv.Atoi(c.Param("absence"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		var body model.Absence
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		body.Id = absenceId
+		absence, err := r.scheduleService(c).UpdateAbsence(body)
+		switch {
+		case errors.Is(err, schedule.ErrAbsenceNotFound):
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, schedule.ErrInvalidAbsence):
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		case err != nil:
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusOK, absence)
+	}
+}
+
+// @Summary Delete absence
+// @Description Delete an absence by its ID
+// @Accept json
+// @Produce json
+// @Param absence path int true "Absence ID"
+// @Success 200 {string} string "Absence Deleted"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "absence not exist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /absences/{absence} [delete]
+func (r *router) deleteAbsence() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !r.authorize(c, policy.ManageAbsences, 0) {
+			return
+		}
+		absenceId, err := strconv.Atoi(c.Param("absence"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		err = r.scheduleService(c).DeleteAbsence(absenceId)
+		if errors.Is(err, schedule.ErrAbsenceNotFound) {
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusOK, "Absence Deleted")
+	}
+}
+
+// @Summary Get absence days of a user
+// @Description List the days a user is away: own vacations and sick leaves and the holidays of the schedule's region
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Param dateFrom query string false "Date From (RFC3339, local date-time or YYYY-MM-DD in the user's timezone)"
+// @Param dateTo query string false "Date To (RFC3339, local date-time or YYYY-MM-DD in the user's timezone, inclusive)"
+// @Param tz query string false "Timezone overriding the user's timezone"
+// @Success 200 {array} model.AbsenceDay "Absence days"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /users/{user}/absence-days [get]
+func (r *router) getAbsenceDays() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.authorize(c, policy.ReadReports, userId) {
+			return
+		}
+		if !r.timeService(c).UserExists(userId) {
+			c.JSON(http.StatusBadRequest, "user not exist")
+			return
+		}
+		days, err := r.scheduleService(c).GetAbsenceDays(userId, c.Request.URL.Query())
+		if errors.Is(err, period.ErrInvalidTimezone) {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusOK, days)
+	}
+}
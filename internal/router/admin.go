@@ -0,0 +1,54 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/passport"
+	"github.com/TimeTracker-Effective-Mobile/internal/policy"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminMiddleware only lets callers through that may manage users.
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := policy.Authorize(principalFrom(c), policy.ManageUsers, policy.Target{}); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, err.Error())
+			return
+		}
+		c.Next()
+	}
+}
+
+// authorize consults the policy about an action on the user, or on nobody in
+// particular for userId 0, and writes a 403 with the reason when it is
+// denied. The user's team is only looked up for managers.
+func (r *router) authorize(c *gin.Context, action policy.Action, userId int) bool {
+	principal := principalFrom(c)
+	target := policy.Target{UserId: userId}
+	if principal.Role == model.RoleManager && userId != 0 && r.timeService(c).UserExists(userId) {
+		user, err := r.timeService(c).GetUser(userId)
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return false
+		}
+		target.TeamId = user.TeamId
+	}
+	if err := policy.Authorize(principal, action, target); err != nil {
+		c.JSON(http.StatusForbidden, err.Error())
+		return false
+	}
+	return true
+}
+
+// maskPassports hides passport numbers from everyone but admins.
+func maskPassports(c *gin.Context, users ...*model.User) {
+	if principalFrom(c).IsAdmin() {
+		return
+	}
+	for _, user := range users {
+		user.PassportNumber = passport.Mask(user.PassportNumber)
+	}
+}
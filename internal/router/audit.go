@@ -0,0 +1,30 @@
+package router
+
+import (
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// audit records that the caller took action on the user. userId is nil for
+// users that no longer exist. The action has already happened, so a failure
+// to record it is only logged.
+func (r *router) audit(c *gin.Context, action string, userId *int, details map[string]any) (model.AuditRecord, bool) {
+	principal := principalFrom(c)
+	record := model.AuditRecord{
+		UserId:       userId,
+		Actor:        principal.Username,
+		CredentialId: &principal.CredentialId,
+		Action:       action,
+		Details:      details,
+	}
+	if principal.APIKeyId != 0 {
+		record.APIKeyId = &principal.APIKeyId
+	}
+	record, err := r.timeService(c).RecordAudit(record)
+	if err != nil {
+		logrus.Infof("audit %s: %s", action, err.Error())
+		return record, false
+	}
+	return record, true
+}
@@ -0,0 +1,423 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/policy"
+	"github.com/TimeTracker-Effective-Mobile/internal/service/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const principalKey = "principal"
+
+type authService interface {
+	Authenticate(username, password string) (model.Principal, error)
+	CreateCredential(username, password string, userId *int, role string, organizationId int) (model.Credential, error)
+	CreateOrganization(name, username, password string) (model.Organization, model.Credential, error)
+	GetOrganizations() ([]model.Organization, error)
+	Login(username, password string) (model.TokenPair, error)
+	Refresh(refreshToken string) (model.TokenPair, error)
+	Logout(refreshToken string) error
+	ParseAccessToken(accessToken string) (model.Principal, error)
+	AuthenticateAPIKey(secret string) (model.Principal, error)
+	CreateAPIKey(principal model.Principal, name string, scopes []string, expiresAt *time.Time) (model.APIKey, error)
+	ListAPIKeys(principal model.Principal) ([]model.APIKey, error)
+	DeleteAPIKey(principal model.Principal, id int) (bool, error)
+}
+
+// AuthMiddleware accepts Basic credentials or a Bearer access token or API
+// key, rejects everything else and stores the authenticated model.Principal
+// in the context.
+func AuthMiddleware(authService authService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, value, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		var principal model.Principal
+		var err error
+		switch strings.ToLower(scheme) {
+		case "basic":
+			username, password, ok := c.Request.BasicAuth()
+			if !ok {
+				unauthorized(c)
+				return
+			}
+			principal, err = authService.Authenticate(username, password)
+		case "bearer":
+			value = strings.TrimSpace(value)
+			if strings.HasPrefix(value, model.APIKeyPrefix) {
+				principal, err = authService.AuthenticateAPIKey(value)
+				break
+			}
+			principal, err = authService.ParseAccessToken(value)
+		default:
+			unauthorized(c)
+			return
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) || errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrInvalidAPIKey) {
+			logrus.Debug(err)
+			unauthorized(c)
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+func unauthorized(c *gin.Context) {
+	c.Writer.Header().Add("WWW-Authenticate", `Basic realm="Time Tracker", charset="UTF-8"`)
+	c.Writer.Header().Add("WWW-Authenticate", `Bearer realm="Time Tracker"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, "Unauthorized")
+}
+
+// principalFrom returns the caller authenticated by the middleware.
+func principalFrom(c *gin.Context) model.Principal {
+	principal, _ := c.Get(principalKey)
+	p, _ := principal.(model.Principal)
+	return p
+}
+
+type createCredentialBody struct {
+	Username string `json:"username" example:"ivanov"`
+	Password string `json:"password" example:"correct horse battery"`
+	UserId   *int   `json:"user_id" example:"1"`
+	Role     string `json:"role" example:"member" enums:"admin,manager,member"`
+}
+
+// @Summary Create a login
+// @Description Create Basic auth credentials in the caller's organization with a role, member by default. Member and manager logins belong to a user of the organization; managers manage the user's team. Admin logins may have no user.
+// @Accept json
+// @Produce json
+// @Param request body createCredentialBody true "Credentials"
+// @Success 201 {object} model.Credential "Created login"
+// @Failure 400 {object} validationErrorResponse "Invalid credentials"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "username already taken"
+// @Failure 500 {string} string "Internal Server Error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/credentials [post]
+func (r *router) createCredential() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		body := createCredentialBody{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		credential, err := r.authService.CreateCredential(strings.TrimSpace(body.Username), body.Password, body.UserId, body.Role, principalFrom(c).OrganizationId)
+		if respondValidationError(c, err) {
+			return
+		}
+		if errors.Is(err, auth.ErrDuplicateUsername) {
+			c.JSON(http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if credential.UserId != nil {
+			r.audit(c, model.AuditLoginCreated, credential.UserId, map[string]any{"role": credential.Role})
+		}
+		c.JSON(http.StatusCreated, credential)
+	}
+}
+
+type createOrganizationBody struct {
+	Name          string `json:"name" example:"Subsidiary"`
+	AdminUsername string `json:"admin_username" example:"petrov"`
+	AdminPassword string `json:"admin_password" example:"correct horse battery"`
+}
+
+type createdOrganization struct {
+	Organization model.Organization `json:"organization"`
+	Admin        model.Credential   `json:"admin"`
+}
+
+// @Summary Create an organization
+// @Description Create an organization with its first admin login. Users, tasks, teams and logins of different organizations never see each other. Only admins of the root organization manage organizations.
+// @Accept json
+// @Produce json
+// @Param request body createOrganizationBody true "Organization"
+// @Success 201 {object} createdOrganization "Created organization"
+// @Failure 400 {object} validationErrorResponse "Invalid organization"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "organization already exists"
+// @Failure 500 {string} string "Internal Server Error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/organizations [post]
+func (r *router) createOrganization() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !r.authorize(c, policy.ManageOrganizations, 0) {
+			return
+		}
+		body := createOrganizationBody{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		organization, admin, err := r.authService.CreateOrganization(body.Name, body.AdminUsername, body.AdminPassword)
+		if respondValidationError(c, err) {
+			return
+		}
+		if errors.Is(err, auth.ErrDuplicateOrganization) || errors.Is(err, auth.ErrDuplicateUsername) {
+			c.JSON(http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusCreated, createdOrganization{Organization: organization, Admin: admin})
+	}
+}
+
+// @Summary List organizations
+// @Description Only admins of the root organization see the organizations.
+// @Produce json
+// @Success 200 {array} model.Organization "Organizations"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/organizations [get]
+func (r *router) getOrganizations() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !r.authorize(c, policy.ManageOrganizations, 0) {
+			return
+		}
+		organizations, err := r.authService.GetOrganizations()
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusOK, organizations)
+	}
+}
+
+type loginBody struct {
+	Username string `json:"username" binding:"required" example:"ivanov"`
+	Password string `json:"password" binding:"required" example:"correct horse battery"`
+}
+
+type refreshTokenBody struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"q3bX9yFh0mJv2S0lQ1nA7d4uZk8cWt5eRr6pYo3iL2s"`
+}
+
+// @Summary Log in
+// @Description Exchange a username and password for a short-lived access token and a refresh token
+// @Accept json
+// @Produce json
+// @Param request body loginBody true "Credentials"
+// @Success 200 {object} model.TokenPair "Tokens"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /auth/login [post]
+func (r *router) login() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		body := loginBody{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		tokens, err := r.authService.Login(body.Username, body.Password)
+		r.respondTokens(c, tokens, err)
+	}
+}
+
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new token pair. The refresh token can only be used once; reusing it revokes every session of the login.
+// @Accept json
+// @Produce json
+// @Param request body refreshTokenBody true "Refresh token"
+// @Success 200 {object} model.TokenPair "Tokens"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /auth/refresh [post]
+func (r *router) refresh() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		body := refreshTokenBody{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		tokens, err := r.authService.Refresh(body.RefreshToken)
+		r.respondTokens(c, tokens, err)
+	}
+}
+
+func (r *router) respondTokens(c *gin.Context, tokens model.TokenPair, err error) {
+	if errors.Is(err, auth.ErrInvalidCredentials) || errors.Is(err, auth.ErrInvalidToken) {
+		c.JSON(http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		logrus.Info(err)
+		c.JSON(http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary Log out
+// @Description Revoke a refresh token. Access tokens issued with it stay valid until they expire.
+// @Accept json
+// @Param request body refreshTokenBody true "Refresh token"
+// @Success 204 "Logged out"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /auth/logout [post]
+func (r *router) logout() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		body := refreshTokenBody{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if err := r.authService.Logout(body.RefreshToken); err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// ScopeMiddleware rejects API keys that were not granted scope. Other
+// callers pass.
+func ScopeMiddleware(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !principalFrom(c).Can(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, "API key lacks scope "+scope)
+			return
+		}
+		c.Next()
+	}
+}
+
+type createAPIKeyBody struct {
+	Name      string     `json:"name" example:"CI bot"`
+	Scopes    []string   `json:"scopes" example:"tasks:write,reports:read"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2025-01-01T00:00:00Z"`
+}
+
+// @Summary Create an API key
+// @Description Create an API key acting for the caller, limited to the given scopes: users:read, users:write, tasks:write, reports:read, schedules:read, schedules:write, admin.
+// @Description The key is only shown in this response. Send it as "Authorization: Bearer <key>".
+// @Accept json
+// @Produce json
+// @Param request body createAPIKeyBody true "API key"
+// @Success 201 {object} model.APIKey "Created key"
+// @Failure 400 {object} validationErrorResponse "Invalid API key"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "API keys cannot manage API keys"
+// @Failure 500 {string} string "Internal Server Error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /api-keys [post]
+func (r *router) createAPIKey() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		body := createAPIKeyBody{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		key, err := r.authService.CreateAPIKey(principalFrom(c), body.Name, body.Scopes, body.ExpiresAt)
+		if respondValidationError(c, err) || respondAPIKeyError(c, err) {
+			return
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusCreated, key)
+	}
+}
+
+// @Summary List API keys
+// @Description List the caller's API keys without the keys themselves
+// @Produce json
+// @Success 200 {array} model.APIKey "API keys"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "API keys cannot manage API keys"
+// @Failure 500 {string} string "Internal Server Error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /api-keys [get]
+func (r *router) getAPIKeys() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		keys, err := r.authService.ListAPIKeys(principalFrom(c))
+		if respondAPIKeyError(c, err) {
+			return
+		}
+		c.JSON(http.StatusOK, keys)
+	}
+}
+
+// @Summary Revoke an API key
+// @Description Delete one of the caller's API keys; admins may delete any key
+// @Param key path int true "API key ID"
+// @Success 204 "API key deleted"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "API keys cannot manage API keys"
+// @Failure 404 {string} string "API key not exist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /api-keys/{key} [delete]
+func (r *router) deleteAPIKey() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		keyId, err := strconv.Atoi(c.Param("key"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		deleted, err := r.authService.DeleteAPIKey(principalFrom(c), keyId)
+		if respondAPIKeyError(c, err) {
+			return
+		}
+		if !deleted {
+			c.JSON(http.StatusNotFound, "API key not exist")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// respondAPIKeyError writes the response for errors of the API key
+// management and reports whether there was one.
+func respondAPIKeyError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, auth.ErrAPIKeyManagement):
+		c.JSON(http.StatusForbidden, err.Error())
+	default:
+		logrus.Info(err)
+		c.JSON(http.StatusInternalServerError, "Internal Server Error")
+	}
+	return true
+}
@@ -0,0 +1,227 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/client/people"
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/repository"
+	"github.com/TimeTracker-Effective-Mobile/internal/service/auth"
+	"github.com/TimeTracker-Effective-Mobile/internal/service/schedule"
+	"github.com/TimeTracker-Effective-Mobile/internal/service/task"
+	"github.com/gin-gonic/gin"
+)
+
+// noPeople stands in for the people API, which these tests never reach.
+type noPeople struct{}
+
+func (noPeople) GetInfo(ctx context.Context, series, number string) (people.Person, error) {
+	return people.Person{}, people.ErrNotFound
+}
+
+func (noPeople) Refresh(ctx context.Context, series, number string) (people.Person, error) {
+	return people.Person{}, people.ErrNotFound
+}
+
+func (noPeople) Forget(series, number string) error {
+	return nil
+}
+
+type noEnrichment struct{}
+
+func (noEnrichment) Enqueue(userId int) error {
+	return nil
+}
+
+// TestOrganizationIsolation calls every endpoint as the admin of one
+// organization with the ids of another one's data. It runs against the
+// database in TEST_POSTGRES_URL, see the postgres package, and is skipped
+// without it.
+func TestOrganizationIsolation(t *testing.T) {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+	t.Setenv("ENCRYPTION_KEYS", "test:dGVzdC1vbmx5LWVuY3J5cHRpb24ta2V5LTAwMDAwMDA=")
+	t.Setenv("ENCRYPTION_ACTIVE_KEY", "test")
+	t.Setenv("ENCRYPTION_INDEX_KEY", "dGVzdC1vbmx5LWJsaW5kLWluZGV4LWtleS0wMDAwMDA=")
+	t.Setenv("JWT_SECRET", "test-only-jwt-secret-0123456789abcdef")
+	gin.SetMode(gin.TestMode)
+	repo, err := repository.Open(url, "file://../../migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authService := auth.New(repo)
+	taskService := task.New(repo, noPeople{}, noEnrichment{})
+	scheduleService := schedule.New(repo)
+	tenants := func(orgId int) Services {
+		scoped := repo.WithTenant(orgId)
+		return Services{Tasks: taskService.WithStorage(scoped), Schedules: scheduleService.WithStorage(scoped)}
+	}
+	router := New(tenants, authService)
+
+	// The marker is in every name and note of the other organization, so
+	// that it shows up in any response leaking its data.
+	marker := fmt.Sprintf("victim%d", time.Now().UnixNano())
+	own, _, err := authService.CreateOrganization("own "+marker, "own-"+marker, "own-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, otherAdmin, err := authService.CreateOrganization("other "+marker, "other-"+marker, "other-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mine := repo.WithTenant(own.Id)
+	theirs := repo.WithTenant(other.Id)
+
+	ownUser := model.User{PassportNumber: testPassport(), Name: "Own", Surname: "User", Timezone: "UTC", Status: model.UserActive}
+	if err := mine.SaveUser(&ownUser); err != nil {
+		t.Fatal(err)
+	}
+	user := model.User{PassportNumber: testPassport(), Name: marker, Surname: marker, Address: marker, Timezone: "UTC", Status: model.UserActive}
+	if err := theirs.SaveUser(&user); err != nil {
+		t.Fatal(err)
+	}
+	stopped, err := theirs.StartNewTask(user.Id, marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := theirs.StopTask(stopped.Id); err != nil {
+		t.Fatal(err)
+	}
+	running, err := theirs.StartNewTask(user.Id, marker+" running")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := theirs.GetSessionsByTasks([]int{stopped.Id})
+	if err != nil || len(sessions) == 0 {
+		t.Fatalf("sessions = %v, %v", sessions, err)
+	}
+	session := sessions[0]
+	sched := model.Schedule{UserId: user.Id, WeeklyHours: 40, WorkDays: []int{1, 2, 3, 4, 5}, DayStart: "09:00", DayEnd: "18:00", Timezone: "UTC"}
+	if err := theirs.SaveSchedule(sched); err != nil {
+		t.Fatal(err)
+	}
+	absence := model.Absence{UserId: &user.Id, Kind: model.AbsenceVacation, DateFrom: "2024-07-01", DateTo: "2024-07-14", Note: marker}
+	if err := theirs.SaveAbsence(&absence); err != nil {
+		t.Fatal(err)
+	}
+	team := model.Team{Name: marker}
+	if _, err := theirs.SaveTeam(&team); err != nil {
+		t.Fatal(err)
+	}
+	key, err := authService.CreateAPIKey(model.Principal{CredentialId: otherAdmin.Id, OrganizationId: other.Id, Role: model.RoleAdmin},
+		marker, []string{model.ScopeUsersRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := func(format string, a ...any) string { return fmt.Sprintf(format, a...) }
+	tests := []struct {
+		method, path, contentType, body string
+	}{
+		{http.MethodGet, "/users", "", ""},
+		{http.MethodGet, id("/users?id=%d", user.Id), "", ""},
+		{http.MethodGet, id("/users/%d", user.Id), "", ""},
+		{http.MethodPut, id("/users/%d", user.Id), "application/json", `{"passportNumber": "` + testPassport() + `", "name": "Changed", "surname": "Changed", "address": "Changed", "timezone": "UTC", "status": "active"}`},
+		{http.MethodPatch, id("/users/%d", user.Id), "application/json", `{"name": "Changed"}`},
+		{http.MethodDelete, id("/users/%d", user.Id), "", ""},
+		{http.MethodPost, id("/users/%d/restore", user.Id), "", ""},
+		{http.MethodGet, id("/users/%d/export", user.Id), "", ""},
+		{http.MethodGet, id("/users/%d/workhours", user.Id), "", ""},
+		{http.MethodGet, id("/users/%d/absence-days", user.Id), "", ""},
+		{http.MethodGet, id("/users/%d/schedule", user.Id), "", ""},
+		{http.MethodPut, id("/users/%d/schedule", user.Id), "application/json", `{"weekly_hours": 20, "work_days": [1], "day_start": "10:00", "day_end": "14:00", "timezone": "UTC"}`},
+		{http.MethodGet, id("/users/%d/overtime", user.Id), "", ""},
+		{http.MethodPost, "/tasks/start-new", "application/json", id(`{"user_id": %d, "name": "intruder"}`, user.Id)},
+		{http.MethodPost, "/tasks/start-existed", "application/json", id(`{"task_id": %d}`, stopped.Id)},
+		{http.MethodPost, "/tasks/stop", "application/json", id(`{"task_id": %d}`, running.Id)},
+		{http.MethodPut, id("/sessions/%d", session.Id), "application/json", `{"started_at": "2024-07-09T09:00:00Z", "stopped_at": "2024-07-09T09:30:00Z"}`},
+		{http.MethodGet, "/absences", "", ""},
+		{http.MethodGet, id("/absences?userId=%d", user.Id), "", ""},
+		{http.MethodGet, id("/absences/%d", absence.Id), "", ""},
+		{http.MethodPut, id("/absences/%d", absence.Id), "application/json", `{"kind": "sick_leave", "date_from": "2024-08-01", "date_to": "2024-08-02"}`},
+		{http.MethodDelete, id("/absences/%d", absence.Id), "", ""},
+		{http.MethodGet, "/admin/teams", "", ""},
+		{http.MethodPut, id("/admin/users/%d/team", ownUser.Id), "application/json", id(`{"team_id": %d}`, team.Id)},
+		{http.MethodPut, id("/admin/users/%d/team", user.Id), "application/json", `{"team_id": null}`},
+		{http.MethodPost, id("/admin/users/%d/refresh", user.Id), "", ""},
+		{http.MethodPost, id("/admin/users/%d/anonymize", user.Id), "", ""},
+		{http.MethodDelete, id("/admin/users/%d", user.Id), "", ""},
+		{http.MethodGet, "/api-keys", "", ""},
+		{http.MethodDelete, id("/api-keys/%d", key.Id), "", ""},
+		{http.MethodGet, "/search?q=" + marker, "", ""},
+		{http.MethodPost, "/users/import?dryRun=true", "text/csv", user.PassportNumber + ",Ivan,Ivanov,,Moscow\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			req.SetBasicAuth("own-"+marker, "own-password")
+			w := httptest.NewRecorder()
+			router.ginRouter.ServeHTTP(w, req)
+			body := w.Body.String()
+			if strings.Contains(body, marker) || strings.Contains(body, `"duplicate"`) {
+				t.Fatalf("response leaks the other organization: %d %s", w.Code, body)
+			}
+			switch {
+			case w.Code == http.StatusNotFound:
+			case w.Code == http.StatusBadRequest && strings.Contains(body, "not exist"):
+			case w.Code == http.StatusOK && tt.method == http.MethodGet:
+				// Listings come back without the other organization's rows.
+			case w.Code == http.StatusOK && strings.HasPrefix(tt.path, "/users/import"):
+			default:
+				t.Errorf("status %d %s, want not found or an empty result", w.Code, body)
+			}
+		})
+	}
+
+	// Nothing of the other organization has changed.
+	got, err := theirs.GetUser(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != marker || got.Address != marker || got.PassportNumber != user.PassportNumber || got.TeamId != nil || got.DeletedAt != nil || got.AnonymizedAt != nil {
+		t.Errorf("user = %+v", got)
+	}
+	if !theirs.IsActiveTask(running.Id) || theirs.IsActiveTask(stopped.Id) {
+		t.Error("tasks were started or stopped")
+	}
+	if tasks, err := theirs.GetTasksByUser(user.Id); err != nil || len(tasks) != 2 {
+		t.Errorf("tasks = %v, %v", tasks, err)
+	}
+	if got, _, err := theirs.GetSession(session.Id); err != nil || !got.StartedAt.Equal(session.StartedAt) {
+		t.Errorf("session = %+v, %v", got, err)
+	}
+	if got, err := theirs.GetSchedule(user.Id); err != nil || got.WeeklyHours != 40 {
+		t.Errorf("schedule = %+v, %v", got, err)
+	}
+	if got, err := theirs.GetAbsence(absence.Id); err != nil || got.Kind != model.AbsenceVacation {
+		t.Errorf("absence = %+v, %v", got, err)
+	}
+	if exists, err := theirs.TeamExists(team.Id); err != nil || !exists {
+		t.Errorf("team exists = %v, %v", exists, err)
+	}
+	if _, err := authService.AuthenticateAPIKey(key.Key); err != nil {
+		t.Errorf("API key: %v", err)
+	}
+	if got, err := mine.GetUser(ownUser.Id); err != nil || got.TeamId != nil {
+		t.Errorf("own user = %+v, %v", got, err)
+	}
+}
+
+// testPassport returns a passport number unlikely to be registered yet.
+func testPassport() string {
+	n := rand.Int63n(1e10)
+	return fmt.Sprintf("%04d %06d", n/1e6, n%1e6)
+}
@@ -0,0 +1,39 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+// setPageHeaders reports the page through X-Next-Cursor, X-Total-Count and an
+// RFC 8288 Link header, keeping list bodies plain arrays.
+func setPageHeaders(c *gin.Context, result pagination.Result) {
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(c.Request.URL, ""))}
+	if result.NextCursor != "" {
+		c.Header("X-Next-Cursor", result.NextCursor)
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(c.Request.URL, result.NextCursor)))
+	}
+	if result.Total != nil {
+		c.Header("X-Total-Count", strconv.Itoa(*result.Total))
+	}
+	c.Header("Link", strings.Join(links, ", "))
+}
+
+// pageURL is the request URL moved to the given cursor, or to the first page
+// when cursor is empty.
+func pageURL(current *url.URL, cursor string) string {
+	query := current.Query()
+	query.Del("page")
+	query.Del("offset")
+	query.Del("cursor")
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	next := url.URL{Path: current.Path, RawQuery: query.Encode()}
+	return next.String()
+}
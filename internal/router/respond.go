@@ -0,0 +1,58 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/client/people"
+	"github.com/TimeTracker-Effective-Mobile/internal/passport"
+	"github.com/TimeTracker-Effective-Mobile/internal/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type validationErrorResponse struct {
+	Error  string            `json:"error" example:"invalid passport number"`
+	Fields map[string]string `json:"fields"`
+}
+
+// respondValidationError writes a 400 with field-level errors when err is a
+// validation error and reports whether it did.
+func respondValidationError(c *gin.Context, err error) bool {
+	var passportErr *passport.ValidationError
+	if errors.As(err, &passportErr) {
+		fields := map[string]string{}
+		for field, msg := range passportErr.Fields {
+			if field != "passportNumber" {
+				field = "passportNumber." + field
+			}
+			fields[field] = msg
+		}
+		c.JSON(http.StatusBadRequest, validationErrorResponse{Error: "invalid passport number", Fields: fields})
+		return true
+	}
+	var validationErr *validation.Error
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, validationErrorResponse{Error: validationErr.Message, Fields: validationErr.Fields})
+		return true
+	}
+	return false
+}
+
+// respondPeopleError maps people API failures to 404, 502 and 504 and
+// reports whether it wrote a response.
+func respondPeopleError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, people.ErrNotFound):
+		c.JSON(http.StatusNotFound, err.Error())
+	case errors.Is(err, people.ErrTimeout):
+		logrus.Info(err)
+		c.JSON(http.StatusGatewayTimeout, "Gateway Timeout")
+	case errors.Is(err, people.ErrUnavailable), errors.Is(err, people.ErrBadResponse), errors.Is(err, people.ErrCircuitOpen):
+		logrus.Info(err)
+		c.JSON(http.StatusBadGateway, "Bad Gateway")
+	default:
+		return false
+	}
+	return true
+}
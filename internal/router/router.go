@@ -1,11 +1,25 @@
 package router
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"os"
+	"sort"
 	"strconv"
+	"time"
 
 	_ "github.com/TimeTracker-Effective-Mobile/docs"
+	"github.com/TimeTracker-Effective-Mobile/internal/duration"
 	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/pagination"
+	"github.com/TimeTracker-Effective-Mobile/internal/period"
+	"github.com/TimeTracker-Effective-Mobile/internal/policy"
+	"github.com/TimeTracker-Effective-Mobile/internal/service/task"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	swaggerFiles "github.com/swaggo/files"
@@ -13,13 +27,16 @@ import (
 )
 
 type router struct {
-	ginRouter   *gin.Engine
-	timeService timeTrackerService
+	ginRouter           *gin.Engine
+	tenants             func(orgId int) Services
+	authService         authService
+	duplicateUserStatus int
+	asyncEnrichment     bool
 }
 
 type timeTrackerService interface {
-	GetUsersInfo(query map[string][]string) ([]model.User, error)
-	GetSortedTaskByUser(userId int, query map[string][]string) ([]model.Task, error)
+	GetUsersInfo(query map[string][]string) ([]model.User, pagination.Result, error)
+	GetSortedTaskByUser(userId int, query map[string][]string) ([]model.Task, pagination.Result, error)
 	StartNewTask(userId int, name string) (model.Task, error)
 	StartExistingTask(taskId int) error
 	TaskExists(taskId int) bool
@@ -27,25 +44,85 @@ type timeTrackerService interface {
 	IsActiveTask(taskId int) bool
 	StopTask(taskId int) (model.Task, error)
 	DeleteUser(userId int) error
+	RestoreUser(userId int) (model.User, error)
+	PurgeUser(userId int) error
+	ExportUser(userId int, query map[string][]string) (model.UserExport, error)
+	AnonymizeUser(userId int) (model.User, error)
+	ImportUsers(ctx context.Context, format string, body io.Reader, dryRun bool) (model.ImportReport, error)
 	UpdateUser(user model.User) error
-	AddUser(passport string) (model.User, error)
+	AddUser(ctx context.Context, passport string, async bool) (model.User, error)
+	GetUser(userId int) (model.User, error)
+	GetTask(taskId int) (model.Task, error)
+	GetSession(sessionId int) (model.Session, error)
+	UpdateSession(sessionId int, startedAt time.Time, stoppedAt *time.Time) (model.Session, error)
+	CreateTeam(name string) (model.Team, error)
+	GetTeams() ([]model.Team, error)
+	SetUserTeam(userId int, teamId *int) (model.User, error)
+	RefreshUser(ctx context.Context, userId int) (model.UserRefresh, error)
+	PatchUser(userId int, patch map[string]json.RawMessage) (model.User, error)
+	Search(query map[string][]string) ([]model.SearchHit, error)
+	RecordAudit(record model.AuditRecord) (model.AuditRecord, error)
 }
 
-func New(timeService timeTrackerService) router {
+// New builds the router. tenants returns the services limited to an
+// organization; every authenticated request gets those of its caller.
+func New(tenants func(orgId int) Services, authService authService) router {
 	router := router{
-		ginRouter:   gin.New(),
-		timeService: timeService,
+		ginRouter:           gin.New(),
+		tenants:             tenants,
+		authService:         authService,
+		duplicateUserStatus: http.StatusOK,
 	}
+	if os.Getenv("DUPLICATE_USER_STATUS") == strconv.Itoa(http.StatusConflict) {
+		router.duplicateUserStatus = http.StatusConflict
+	}
+	router.asyncEnrichment = os.Getenv("ENRICHMENT_MODE") == "async"
 	router.ginRouter.Use(CORSMiddleware())
-	router.ginRouter.GET("/users", router.getUsers())
-	router.ginRouter.GET("/users/:user/workhours", router.getWorkHoursByUser())
-	router.ginRouter.POST("/tasks/start-new", router.startNewTask())
-	router.ginRouter.POST("/tasks/start-existed", router.startExistedTask())
-	router.ginRouter.POST("/tasks/stop", router.stopTask())
-	router.ginRouter.DELETE("/users/:user", router.deleteUser())
-	router.ginRouter.PUT("/users/:user", router.updateUser())
-	router.ginRouter.POST("/users", router.addUser())
+	// Routes only get the middlewares added before them: the docs and the
+	// token endpoints stay public.
 	router.initSwagger()
+	auth := router.ginRouter.Group("/auth")
+	auth.POST("/login", router.login())
+	auth.POST("/refresh", router.refresh())
+	auth.POST("/logout", router.logout())
+	router.ginRouter.Use(AuthMiddleware(authService), TenantMiddleware(tenants))
+	router.ginRouter.GET("/users", ScopeMiddleware(model.ScopeUsersRead), router.getUsers())
+	router.ginRouter.GET("/users/:user", ScopeMiddleware(model.ScopeUsersRead), router.getUser())
+	router.ginRouter.GET("/users/:user/workhours", ScopeMiddleware(model.ScopeReportsRead), router.getWorkHoursByUser())
+	router.ginRouter.GET("/search", ScopeMiddleware(model.ScopeUsersRead), router.search())
+	router.ginRouter.POST("/tasks/start-new", ScopeMiddleware(model.ScopeTasksWrite), router.startNewTask())
+	router.ginRouter.POST("/tasks/start-existed", ScopeMiddleware(model.ScopeTasksWrite), router.startExistedTask())
+	router.ginRouter.POST("/tasks/stop", ScopeMiddleware(model.ScopeTasksWrite), router.stopTask())
+	router.ginRouter.PUT("/sessions/:session", ScopeMiddleware(model.ScopeTasksWrite), router.updateSession())
+	router.ginRouter.DELETE("/users/:user", ScopeMiddleware(model.ScopeUsersWrite), router.deleteUser())
+	router.ginRouter.PUT("/users/:user", ScopeMiddleware(model.ScopeUsersWrite), router.updateUser())
+	router.ginRouter.PATCH("/users/:user", ScopeMiddleware(model.ScopeUsersWrite), router.patchUser())
+	router.ginRouter.POST("/users", ScopeMiddleware(model.ScopeUsersWrite), router.addUser())
+	router.ginRouter.POST("/users/import", ScopeMiddleware(model.ScopeUsersWrite), router.importUsers())
+	router.ginRouter.POST("/users/:user/restore", ScopeMiddleware(model.ScopeUsersWrite), router.restoreUser())
+	router.ginRouter.GET("/users/:user/export", ScopeMiddleware(model.ScopeUsersRead), router.exportUser())
+	router.ginRouter.GET("/users/:user/schedule", ScopeMiddleware(model.ScopeSchedulesRead), router.getSchedule())
+	router.ginRouter.PUT("/users/:user/schedule", ScopeMiddleware(model.ScopeSchedulesWrite), router.saveSchedule())
+	router.ginRouter.GET("/users/:user/overtime", ScopeMiddleware(model.ScopeReportsRead), router.getOvertime())
+	router.ginRouter.GET("/users/:user/absence-days", ScopeMiddleware(model.ScopeReportsRead), router.getAbsenceDays())
+	router.ginRouter.GET("/absences", ScopeMiddleware(model.ScopeSchedulesRead), router.getAbsences())
+	router.ginRouter.POST("/absences", ScopeMiddleware(model.ScopeSchedulesWrite), router.createAbsence())
+	router.ginRouter.GET("/absences/:absence", ScopeMiddleware(model.ScopeSchedulesRead), router.getAbsence())
+	router.ginRouter.PUT("/absences/:absence", ScopeMiddleware(model.ScopeSchedulesWrite), router.updateAbsence())
+	router.ginRouter.DELETE("/absences/:absence", ScopeMiddleware(model.ScopeSchedulesWrite), router.deleteAbsence())
+	router.ginRouter.POST("/api-keys", router.createAPIKey())
+	router.ginRouter.GET("/api-keys", router.getAPIKeys())
+	router.ginRouter.DELETE("/api-keys/:key", router.deleteAPIKey())
+	admin := router.ginRouter.Group("/admin", ScopeMiddleware(model.ScopeAdmin), AdminMiddleware())
+	admin.POST("/users/:user/refresh", router.refreshUser())
+	admin.DELETE("/users/:user", router.purgeUser())
+	admin.POST("/users/:user/anonymize", router.anonymizeUser())
+	admin.POST("/credentials", router.createCredential())
+	admin.POST("/teams", router.createTeam())
+	admin.GET("/teams", router.getTeams())
+	admin.PUT("/users/:user/team", router.setUserTeam())
+	admin.POST("/organizations", router.createOrganization())
+	admin.GET("/organizations", router.getOrganizations())
 
 	return router
 }
@@ -64,7 +141,8 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, DELETE, OPTIONS, GET, PUT")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Link, X-Next-Cursor, X-Total-Count")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, DELETE, OPTIONS, GET, PUT, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -79,27 +157,97 @@ func CORSMiddleware() gin.HandlerFunc {
 // @Description Retrieve a list of users based on query parameters
 // @Accept json
 // @Produce json
+// @Description Filters are combined with AND. Repeat a parameter to match any of its values,
+// @Description append ~ to the name for a case-insensitive substring match (name~=pet),
+// @Description ^ for a prefix match (surname^=iva) or [in] for a comma-separated list (id[in]=1,2,3).
+// @Description Passport numbers and addresses are encrypted: passportNumber only matches exactly and address can't be filtered or sorted on.
+// @Description Passport numbers are masked (**** ***890) for everyone but admins. Managers only see their team, members only themselves.
 // @Param id query string false "ID"
 // @Param name query string false "name"
-// @Param passportNumber query string false "passportNumber"
+// @Param passportNumber query string false "passportNumber, exact match only"
 // @Param surname query string false "surname"
 // @Param patronymic query string false "patronymic"
-// @Param address query string false "address"
+// @Param timezone query string false "timezone"
+// @Param status query string false "status" Enums(active, pending, failed)
+// @Param team_id query int false "team_id, 0 for users without a team"
+// @Param sort query string false "Comma-separated fields to sort by, prefixed with - for descending order, e.g. surname,-id. Ties are broken by id"
 // @Param page query string false "page"
-// @Param limit query string false "limit"
+// @Param limit query string false "Page size, capped by PAGINATION_MAX_LIMIT"
 // @Param offset query string false "offset"
+// @Param cursor query string false "Cursor from X-Next-Cursor; takes precedence over page and offset"
+// @Param total query bool false "Count matching users into X-Total-Count"
 // @Success 200 {array} model.User "List of users"
+// @Header 200 {string} X-Next-Cursor "Cursor of the next page, absent on the last page"
+// @Header 200 {integer} X-Total-Count "Number of matching users when total is set"
+// @Header 200 {string} Link "RFC 8288 links to the first and next pages"
 // @Failure 400 {string} string "Bad request"
+// @Failure 400 {object} validationErrorResponse "Invalid filter"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
 // @Router /users [get]
 func (r *router) getUsers() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		query := c.Request.URL.Query()
-		user, err := r.timeService.GetUsersInfo(query)
+		if err := policy.RestrictUserQuery(principalFrom(c), query); err != nil {
+			c.JSON(http.StatusForbidden, err.Error())
+			return
+		}
+		user, page, err := r.timeService(c).GetUsersInfo(query)
+		if respondValidationError(c, err) {
+			return
+		}
 		if err != nil {
 			logrus.Debug(err)
 			c.JSON(http.StatusBadRequest, "Bad request")
 			return
 		}
+		for i := range user {
+			maskPassports(c, &user[i])
+		}
+		setPageHeaders(c, page)
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+// @Summary Get user
+// @Description Retrieve a single user by their ID. Users created asynchronously stay in status pending until enrichment succeeds or fails.
+// @Description The passport number is masked for everyone but admins. Members can only see themselves, managers also their team.
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Success 200 {object} model.User "User"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "user not exist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /users/{user} [get]
+func (r *router) getUser() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.authorize(c, policy.ReadUser, userId) {
+			return
+		}
+		if !r.timeService(c).UserExists(userId) {
+			c.JSON(http.StatusNotFound, "user not exist")
+			return
+		}
+		user, err := r.timeService(c).GetUser(userId)
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		maskPassports(c, &user)
 		c.JSON(http.StatusOK, user)
 	}
 }
@@ -109,10 +257,27 @@ func (r *router) getUsers() func(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param user path int true "User ID"
-// @Param dateFrom query string false "Date From"
-// @Param dateTo query string false "Date To"
+// @Param dateFrom query string false "Date From (RFC3339, local date-time or YYYY-MM-DD in the user's timezone)"
+// @Param dateTo query string false "Date To (RFC3339, local date-time or YYYY-MM-DD in the user's timezone, inclusive)"
+// @Param tz query string false "Timezone overriding the user's timezone"
+// @Param rounding query string false "Rounding increment for rounded_duration, e.g. 6m, 15m or none"
+// @Param roundingMode query string false "Rounding direction" Enums(up, down, nearest)
+// @Param roundingScope query string false "Round every session or the task total" Enums(session, task)
+// @Param durationFormat query string false "Add formatted_duration with hours, minutes and a value in this format" Enums(seconds, hhmm, iso8601)
+// @Param name query string false "Task name; name~ and name^ match substrings and prefixes"
+// @Param limit query string false "Page size, up to PAGINATION_MAX_LIMIT. Without limit, page, offset or cursor every task is returned"
+// @Param cursor query string false "Cursor from X-Next-Cursor"
+// @Param total query bool false "Count matching tasks into X-Total-Count"
 // @Success 200 {array} model.Task "List of sorted tasks"
+// @Header 200 {string} X-Next-Cursor "Cursor of the next page, absent on the last page"
+// @Header 200 {integer} X-Total-Count "Number of matching tasks when total is set"
+// @Header 200 {string} Link "RFC 8288 links to the first and next pages"
 // @Failure 400 {string} string "Bad request"
+// @Failure 400 {object} validationErrorResponse "Invalid filter"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
 // @Router /users/{user}/workhours [get]
 func (r *router) getWorkHoursByUser() func(c *gin.Context) {
 	return func(c *gin.Context) {
@@ -122,56 +287,127 @@ func (r *router) getWorkHoursByUser() func(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, "Bad request")
 			return
 		}
-		if !r.timeService.UserExists(userId) {
+		if !r.authorize(c, policy.ReadReports, userId) {
+			return
+		}
+		if !r.timeService(c).UserExists(userId) {
 			c.JSON(http.StatusBadRequest, "user not exist")
 			return
 		}
 		query := c.Request.URL.Query()
-		tasks, err := r.timeService.GetSortedTaskByUser(userId, query)
+		format, err := duration.FormatFromQuery(query)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		tasks, page, err := r.timeService(c).GetSortedTaskByUser(userId, query)
+		if respondValidationError(c, err) {
+			return
+		}
+		if errors.Is(err, task.ErrInvalidPeriod) || errors.Is(err, period.ErrInvalidTimezone) || errors.Is(err, duration.ErrInvalidRounding) {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
 		if err != nil {
 			logrus.Debug(err)
 			c.JSON(http.StatusBadRequest, "Bad request")
 			return
 		}
+		for i := range tasks {
+			format.Apply(&tasks[i])
+		}
+		setPageHeaders(c, page)
 		c.JSON(http.StatusOK, tasks)
 	}
 }
 
+// @Summary Search users and tasks
+// @Description Full-text search over user names, surnames and patronymics and over task names. Addresses are encrypted and not searchable.
+// @Description Words are matched with Russian and English stemming, misspellings by trigram similarity
+// @Accept json
+// @Produce json
+// @Param q query string true "Search query; supports quoted phrases, or and -word"
+// @Param type query string false "Only return hits of this type" Enums(user, task)
+// @Param limit query string false "Maximum number of hits, capped by PAGINATION_MAX_LIMIT"
+// @Success 200 {array} model.SearchHit "Hits, best match first"
+// @Failure 400 {object} validationErrorResponse "Invalid query"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /search [get]
+func (r *router) search() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !r.authorize(c, policy.SearchUsers, 0) {
+			return
+		}
+		hits, err := r.timeService(c).Search(c.Request.URL.Query())
+		if respondValidationError(c, err) {
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusOK, hits)
+	}
+}
+
 type startNewTaskBody struct {
+	// UserId defaults to the caller's own user.
 	UserId int    `json:"user_id"`
 	Name   string `json:"name"`
 }
 
 // @Summary Start New Task
-// @Description Starts a new task
+// @Description Starts a new task. Without user_id the task is started for the caller's own user.
 // @Accept json
 // @Produce json
 // @Param task body startNewTaskBody true "Task details"
+// @Param durationFormat query string false "Add formatted_duration with hours, minutes and a value in this format" Enums(seconds, hhmm, iso8601)
 // @Success 200 {string} string "Task Started"
 // @Success 201 {object} model.Task
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
 // @Router /tasks/start-new [post]
 func (r *router) startNewTask() func(c *gin.Context) {
 	return func(c *gin.Context) {
+		format, err := duration.FormatFromQuery(c.Request.URL.Query())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
 		body := startNewTaskBody{}
-		err := c.ShouldBindJSON(&body)
+		err = c.ShouldBindJSON(&body)
+		if principal := principalFrom(c); err == nil && body.UserId == 0 && principal.UserId != nil {
+			body.UserId = *principal.UserId
+		}
 		if err != nil || body.Name == "" && body.UserId == 0 {
 			logrus.Debug(err)
 			c.JSON(http.StatusBadRequest, "Bad request")
 			return
 		}
 		var Task model.Task
-		if !r.timeService.UserExists(body.UserId) {
+		if !r.timeService(c).UserExists(body.UserId) {
 			c.JSON(http.StatusBadRequest, "user not exist")
 			return
 		}
-		Task, err = r.timeService.StartNewTask(body.UserId, body.Name)
+		if !r.authorize(c, policy.TrackTime, body.UserId) {
+			return
+		}
+		Task, err = r.timeService(c).StartNewTask(body.UserId, body.Name)
 		if err != nil {
 			logrus.Info(err)
 			c.JSON(http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
+		format.Apply(&Task)
 		c.JSON(http.StatusCreated, Task)
 
 	}
@@ -190,6 +426,10 @@ type startExistedTaskBody struct {
 // @Success 201 {object} model.Task
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
 // @Router /tasks/start-existed [post]
 func (r *router) startExistedTask() func(c *gin.Context) {
 	return func(c *gin.Context) {
@@ -200,11 +440,18 @@ func (r *router) startExistedTask() func(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, "Bad request")
 			return
 		}
-		if !r.timeService.TaskExists(body.TaskId) {
+		if !r.timeService(c).TaskExists(body.TaskId) {
 			c.JSON(http.StatusBadRequest, "task not exist")
 			return
 		}
-		err = r.timeService.StartExistingTask(body.TaskId)
+		if !r.mayTrackTask(c, body.TaskId) {
+			return
+		}
+		if r.timeService(c).IsActiveTask(body.TaskId) {
+			c.JSON(http.StatusBadRequest, "task already active")
+			return
+		}
+		err = r.timeService(c).StartExistingTask(body.TaskId)
 		if err != nil {
 			logrus.Info(err)
 			c.JSON(http.StatusInternalServerError, "Internal Server Error")
@@ -215,6 +462,18 @@ func (r *router) startExistedTask() func(c *gin.Context) {
 	}
 }
 
+// mayTrackTask checks that the caller may start and stop the task and
+// writes a 403 when not.
+func (r *router) mayTrackTask(c *gin.Context, taskId int) bool {
+	task, err := r.timeService(c).GetTask(taskId)
+	if err != nil {
+		logrus.Info(err)
+		c.JSON(http.StatusInternalServerError, "Internal Server Error")
+		return false
+	}
+	return r.authorize(c, policy.TrackTime, task.Owner.Id)
+}
+
 type stopTaskBody struct {
 	TaskId int `json:"task_id"`
 }
@@ -224,39 +483,120 @@ type stopTaskBody struct {
 // @Accept json
 // @Produce json
 // @Param request body stopTaskBody true "Task stop request"
+// @Param durationFormat query string false "Add formatted_duration with hours, minutes and a value in this format" Enums(seconds, hhmm, iso8601)
 // @Success 200 {object} model.Task "Stopped task"
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
 // @Router /tasks/stop [post]
 func (r *router) stopTask() func(c *gin.Context) {
 	return func(c *gin.Context) {
+		format, err := duration.FormatFromQuery(c.Request.URL.Query())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
 		body := stopTaskBody{}
-		err := c.ShouldBindJSON(&body)
+		err = c.ShouldBindJSON(&body)
 		if err != nil || body.TaskId == 0 {
 			logrus.Debug(err)
 			c.JSON(http.StatusBadRequest, "Bad request")
 			return
 		}
-		if !r.timeService.TaskExists(body.TaskId) {
+		if !r.timeService(c).TaskExists(body.TaskId) {
 			c.JSON(http.StatusBadRequest, "task not exist")
 			return
 		}
-		if !r.timeService.IsActiveTask(body.TaskId) {
+		if !r.mayTrackTask(c, body.TaskId) {
+			return
+		}
+		if !r.timeService(c).IsActiveTask(body.TaskId) {
 			c.JSON(http.StatusBadRequest, "task not active")
 			return
 		}
-		task, err := r.timeService.StopTask(body.TaskId)
+		task, err := r.timeService(c).StopTask(body.TaskId)
 		if err != nil {
 			logrus.Info(err)
 			c.JSON(http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
+		format.Apply(&task)
 		c.JSON(http.StatusOK, task)
 	}
 }
 
+type updateSessionBody struct {
+	StartedAt time.Time  `json:"started_at" example:"2024-07-09T09:00:00Z"`
+	StoppedAt *time.Time `json:"stopped_at" example:"2024-07-09T12:30:00Z"`
+}
+
+// @Summary Edit a session
+// @Description Correct when a tracked session started and stopped; the task's duration follows. Running sessions only take a new start. Managers can edit the sessions of their team.
+// @Accept json
+// @Produce json
+// @Param session path int true "Session ID"
+// @Param request body updateSessionBody true "New start and stop"
+// @Success 200 {object} model.Session "Updated session"
+// @Failure 400 {object} validationErrorResponse "Invalid session"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "session not exist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /sessions/{session} [put]
+func (r *router) updateSession() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		sessionId, err := strconv.Atoi(c.Param("session"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		body := updateSessionBody{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		session, err := r.timeService(c).GetSession(sessionId)
+		if errors.Is(err, task.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		sessionTask, err := r.timeService(c).GetTask(session.TaskId)
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if !r.authorize(c, policy.EditSessions, sessionTask.Owner.Id) {
+			return
+		}
+		session, err = r.timeService(c).UpdateSession(sessionId, body.StartedAt, body.StoppedAt)
+		if respondValidationError(c, err) {
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		r.audit(c, model.AuditSessionEdited, &sessionTask.Owner.Id, map[string]any{"session_id": sessionId})
+		c.JSON(http.StatusOK, session)
+	}
+}
+
 // @Summary Delete a user
-// @Description Delete a user by their ID
+// @Description Soft-delete a user by their ID. The user disappears from listings but its tasks are kept and it can be restored
 // @Accept json
 // @Produce json
 // @Param user path int true "User ID"
@@ -264,6 +604,10 @@ func (r *router) stopTask() func(c *gin.Context) {
 // @Failure 400 {string} string "Bad request"
 // @Failure 400 {string} string "user not exist"
 // @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
 // @Router /users/{user} [delete]
 func (r *router) deleteUser() func(c *gin.Context) {
 	return func(c *gin.Context) {
@@ -273,31 +617,273 @@ func (r *router) deleteUser() func(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, "Bad request")
 			return
 		}
-		if !r.timeService.UserExists(userId) {
+		if !r.authorize(c, policy.ManageUsers, userId) {
+			return
+		}
+		if !r.timeService(c).UserExists(userId) {
 			c.JSON(http.StatusBadRequest, "user not exist")
 			return
 		}
-		err = r.timeService.DeleteUser(userId)
+		err = r.timeService(c).DeleteUser(userId)
 		if err != nil {
 			logrus.Info(err)
 			c.JSON(http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
+		r.audit(c, model.AuditUserDeleted, &userId, nil)
 		c.JSON(http.StatusOK, "User Deleted")
 
 	}
 }
 
+const maxImportSize = 10 << 20
+
+// @Summary Import users
+// @Description Register many users at once from CSV (passportNumber,name,surname,patronymic,address, header optional) or JSON lines ({"passportNumber": "1234 567890", "name": ...}).
+// @Description Rows with a name and surname are stored as given, the others are enriched from the people API, IMPORT_CONCURRENCY at a time.
+// @Description The report lists every row as created, duplicate, invalid, enrichment_failed or error. A dry run only validates and checks for duplicates
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param format query string false "Input format, taken from Content-Type by default" Enums(csv, jsonl)
+// @Param dryRun query bool false "Validate without calling the people API or creating users"
+// @Success 200 {object} model.ImportReport "Per-row results"
+// @Failure 400 {string} string "Bad request"
+// @Failure 413 {string} string "Request Entity Too Large"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /users/import [post]
+func (r *router) importUsers() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !r.authorize(c, policy.ManageUsers, 0) {
+			return
+		}
+		format := c.Query("format")
+		if format == "" {
+			mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+			switch mediaType {
+			case "text/csv":
+				format = task.ImportCSV
+			case "application/x-ndjson", "application/jsonl", "application/json-lines":
+				format = task.ImportJSONLines
+			}
+		}
+		dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+		body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+		report, err := r.timeService(c).ImportUsers(c.Request.Context(), format, body, dryRun)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, "Request Entity Too Large")
+			return
+		}
+		if errors.Is(err, task.ErrInvalidImport) {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !dryRun {
+			for _, row := range report.Rows {
+				if row.Status == model.ImportCreated {
+					r.audit(c, model.AuditUserCreated, &row.UserId, map[string]any{"source": "import"})
+				}
+			}
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// @Summary Restore a user
+// @Description Restore a soft-deleted user together with its tasks
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Success 200 {object} model.User "Restored user"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "user not exist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /users/{user}/restore [post]
+func (r *router) restoreUser() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.authorize(c, policy.ManageUsers, userId) {
+			return
+		}
+		user, err := r.timeService(c).RestoreUser(userId)
+		if errors.Is(err, task.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		r.audit(c, model.AuditUserRestored, &userId, nil)
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+// @Summary Export user data
+// @Description Download everything stored about a user, including deleted ones: profile, schedule, absences, tasks, sessions and the cached people API response
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Param rounding query string false "Rounding increment for rounded_duration, e.g. 6m, 15m or none"
+// @Param roundingMode query string false "Rounding direction" Enums(up, down, nearest)
+// @Param roundingScope query string false "Round every session or the task total" Enums(session, task)
+// @Success 200 {object} model.UserExport "User data"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "user not exist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /users/{user}/export [get]
+func (r *router) exportUser() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.authorize(c, policy.ExportUser, userId) {
+			return
+		}
+		export, err := r.timeService(c).ExportUser(userId, c.Request.URL.Query())
+		if errors.Is(err, task.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, duration.ErrInvalidRounding) {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if record, ok := r.audit(c, model.AuditUserExported, &userId, nil); ok {
+			export.Audit = append(export.Audit, record)
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d.json"`, userId))
+		c.JSON(http.StatusOK, export)
+	}
+}
+
+// @Summary Anonymize a user
+// @Description Erase the passport number, name, surname, patronymic, address and absence notes of a user. Tasks and sessions are kept so accounting totals stay intact
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Success 200 {object} model.User "Anonymized user"
+// @Failure 400 {string} string "Bad request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "user not exist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/users/{user}/anonymize [post]
+func (r *router) anonymizeUser() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.authorize(c, policy.ManageUsers, userId) {
+			return
+		}
+		user, err := r.timeService(c).AnonymizeUser(userId)
+		if errors.Is(err, task.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		r.audit(c, model.AuditUserAnonymized, &userId, nil)
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+// @Summary Purge a user
+// @Description Permanently delete a user, deleted or not, with all of its tasks, sessions, schedule and absences
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Success 200 {string} string "User Purged"
+// @Failure 400 {string} string "Bad request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "user not exist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/users/{user} [delete]
+func (r *router) purgeUser() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.authorize(c, policy.ManageUsers, userId) {
+			return
+		}
+		err = r.timeService(c).PurgeUser(userId)
+		if errors.Is(err, task.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		r.audit(c, model.AuditUserPurged, nil, map[string]any{"user_id": userId})
+		c.JSON(http.StatusOK, "User Purged")
+	}
+}
+
 // @Summary Update a user
-// @Description Update user information by their ID
+// @Description Replace all user information by their ID; use PATCH to change single fields. A pending user is activated and its enrichment discarded.
 // @Accept json
 // @Produce json
 // @Param user path int true "User ID"
 // @Param request body model.User true "User update information"
 // @Success 200 {string} string "User Updated"
 // @Failure 400 {string} string "Bad request"
+// @Failure 400 {object} validationErrorResponse "Invalid passport number"
 // @Failure 400 {string} string "user not exist"
+// @Failure 409 {string} string "passport already registered"
 // @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
 // @Router /users/{user} [put]
 func (r *router) updateUser() func(c *gin.Context) {
 	return func(c *gin.Context) {
@@ -307,6 +893,9 @@ func (r *router) updateUser() func(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, "Bad request")
 			return
 		}
+		if !r.authorize(c, policy.ManageUsers, userId) {
+			return
+		}
 		var user model.User
 
 		if err := c.ShouldBindJSON(&user); err != nil {
@@ -315,48 +904,336 @@ func (r *router) updateUser() func(c *gin.Context) {
 			return
 		}
 		user.Id = userId
-		if !r.timeService.UserExists(userId) {
+		if !r.timeService(c).UserExists(userId) {
 			c.JSON(http.StatusBadRequest, "user not exist")
 			return
 		}
-		err = r.timeService.UpdateUser(user)
+		err = r.timeService(c).UpdateUser(user)
+		if respondValidationError(c, err) {
+			return
+		}
+		if errors.Is(err, period.ErrInvalidTimezone) {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, task.ErrDuplicateUser) {
+			c.JSON(http.StatusConflict, err.Error())
+			return
+		}
 		if err != nil {
 			logrus.Info(err)
 			c.JSON(http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
+		r.audit(c, model.AuditUserUpdated, &userId, nil)
 		c.JSON(http.StatusOK, "User Updated")
 	}
 }
 
+// @Summary Partially update a user
+// @Description Apply a JSON Merge Patch to a user: only the provided fields change and null clears patronymic. A pending user is activated and its enrichment discarded.
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Param request body model.User true "Fields to change"
+// @Success 200 {object} model.User "Updated user"
+// @Failure 400 {string} string "Bad request"
+// @Failure 400 {object} validationErrorResponse "Invalid fields"
+// @Failure 404 {string} string "user not exist"
+// @Failure 409 {string} string "passport already registered"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /users/{user} [patch]
+func (r *router) patchUser() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.authorize(c, policy.ManageUsers, userId) {
+			return
+		}
+		patch := map[string]json.RawMessage{}
+		if err := c.ShouldBindJSON(&patch); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.timeService(c).UserExists(userId) {
+			c.JSON(http.StatusNotFound, "user not exist")
+			return
+		}
+		user, err := r.timeService(c).PatchUser(userId, patch)
+		if respondValidationError(c, err) {
+			return
+		}
+		if errors.Is(err, task.ErrDuplicateUser) {
+			c.JSON(http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		fields := make([]string, 0, len(patch))
+		for field := range patch {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		r.audit(c, model.AuditUserUpdated, &userId, map[string]any{"fields": fields})
+		c.JSON(http.StatusOK, user)
+	}
+}
+
 type addNewUserBody struct {
 	PassportNumber string `json:"passportNumber" example:"1234 567890"`
 }
 
 // @Summary Add a new user
-// @Description Create a new user with the given passport number
+// @Description Create a new user with the given passport number ("1234 567890", "1234567890" or "1234-567890"). In async mode poll GET /users/{user} until the status leaves pending, or configure ENRICHMENT_WEBHOOK_URL.
+// @Description When the enrichment queue is full the user is created with status failed; POST /admin/users/{user}/refresh enriches it later.
+// @Description If the passport belongs to a deleted user the response is 409 with a message naming the user to restore
 // @Accept json
 // @Produce json
 // @Param request body addNewUserBody true "User creation request"
+// @Param async query bool false "Create the user as pending and enrich it in the background (default from ENRICHMENT_MODE)"
+// @Success 200 {object} model.User "Passport already registered, existing user"
 // @Success 201 {object} model.User "Created user"
+// @Success 202 {object} model.User "Created pending user, enrichment runs in the background"
 // @Failure 400 {string} string "Bad request"
+// @Failure 400 {object} validationErrorResponse "Invalid passport number"
+// @Failure 404 {string} string "person not found"
+// @Failure 409 {object} model.User "Passport already registered, existing user (when DUPLICATE_USER_STATUS=409)"
 // @Failure 500 {string} string "Internal Server Error"
+// @Failure 502 {string} string "Bad Gateway"
+// @Failure 504 {string} string "Gateway Timeout"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
 // @Router /users [post]
 func (r *router) addUser() func(c *gin.Context) {
 	return func(c *gin.Context) {
+		if !r.authorize(c, policy.ManageUsers, 0) {
+			return
+		}
 		data := addNewUserBody{}
 		if err := c.ShouldBindJSON(&data); err != nil {
 			logrus.Debug(err)
 			c.JSON(http.StatusBadRequest, "Bad request")
 			return
 		}
-		user, err := r.timeService.AddUser(data.PassportNumber)
+		async := r.asyncEnrichment
+		if val, err := strconv.ParseBool(c.Query("async")); err == nil {
+			async = val
+		}
+		user, err := r.timeService(c).AddUser(c.Request.Context(), data.PassportNumber, async)
+		if respondValidationError(c, err) {
+			return
+		}
+		if errors.Is(err, task.ErrDuplicateUser) {
+			c.JSON(r.duplicateUserStatus, user)
+			return
+		}
+		if errors.Is(err, task.ErrDeletedUser) {
+			c.JSON(http.StatusConflict, err.Error())
+			return
+		}
+		if respondPeopleError(c, err) {
+			return
+		}
 		if err != nil {
 			logrus.Info(err)
 			c.JSON(http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
+		r.audit(c, model.AuditUserCreated, &user.Id, nil)
+		if user.Status == model.UserPending {
+			c.JSON(http.StatusAccepted, user)
+			return
+		}
 		c.JSON(http.StatusCreated, user)
 	}
 
 }
+
+// @Summary Refresh user profile
+// @Description Re-fetch the user's profile from the people API bypassing the cache and update the fields that changed
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Success 200 {object} model.UserRefresh "Updated user and changed fields"
+// @Failure 400 {string} string "Bad request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "user not exist"
+// @Failure 409 {string} string "user is anonymized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 502 {string} string "Bad Gateway"
+// @Failure 504 {string} string "Gateway Timeout"
+// @Failure 401 {string} string "Unauthorized"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/users/{user}/refresh [post]
+func (r *router) refreshUser() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.authorize(c, policy.ManageUsers, userId) {
+			return
+		}
+		if !r.timeService(c).UserExists(userId) {
+			c.JSON(http.StatusNotFound, "user not exist")
+			return
+		}
+		result, err := r.timeService(c).RefreshUser(c.Request.Context(), userId)
+		if errors.Is(err, task.ErrAnonymizedUser) {
+			c.JSON(http.StatusConflict, err.Error())
+			return
+		}
+		if respondValidationError(c, err) {
+			return
+		}
+		if respondPeopleError(c, err) {
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if len(result.Changes) > 0 {
+			fields := make([]string, 0, len(result.Changes))
+			for field := range result.Changes {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			r.audit(c, model.AuditUserRefreshed, &userId, map[string]any{"fields": fields})
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+type createTeamBody struct {
+	Name string `json:"name" example:"Backend"`
+}
+
+// @Summary Create a team
+// @Description Create a team. Managers see the reports of the users in their team.
+// @Accept json
+// @Produce json
+// @Param request body createTeamBody true "Team"
+// @Success 201 {object} model.Team "Created team"
+// @Failure 400 {object} validationErrorResponse "Invalid team"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "team already exists"
+// @Failure 500 {string} string "Internal Server Error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/teams [post]
+func (r *router) createTeam() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		body := createTeamBody{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		team, err := r.timeService(c).CreateTeam(body.Name)
+		if respondValidationError(c, err) {
+			return
+		}
+		if errors.Is(err, task.ErrDuplicateTeam) {
+			c.JSON(http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusCreated, team)
+	}
+}
+
+// @Summary List teams
+// @Produce json
+// @Success 200 {array} model.Team "Teams"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/teams [get]
+func (r *router) getTeams() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		teams, err := r.timeService(c).GetTeams()
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusOK, teams)
+	}
+}
+
+type setUserTeamBody struct {
+	TeamId *int `json:"team_id" example:"1"`
+}
+
+// @Summary Move a user to a team
+// @Description Put the user into a team, or out of any team with a null team_id
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Param request body setUserTeamBody true "Team"
+// @Success 200 {object} model.User "Updated user"
+// @Failure 400 {object} validationErrorResponse "Invalid team"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "user not exist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/users/{user}/team [put]
+func (r *router) setUserTeam() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		body := setUserTeamBody{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		user, err := r.timeService(c).SetUserTeam(userId, body.TeamId)
+		if respondValidationError(c, err) {
+			return
+		}
+		if errors.Is(err, task.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		r.audit(c, model.AuditTeamChanged, &userId, map[string]any{"team_id": body.TeamId})
+		c.JSON(http.StatusOK, user)
+	}
+}
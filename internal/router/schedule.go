@@ -0,0 +1,174 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/period"
+	"github.com/TimeTracker-Effective-Mobile/internal/policy"
+	"github.com/TimeTracker-Effective-Mobile/internal/service/schedule"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type scheduleService interface {
+	GetSchedule(userId int) (model.Schedule, error)
+	SaveSchedule(schedule model.Schedule) error
+	GetOvertimeReport(userId int, query map[string][]string) (model.OvertimeReport, error)
+	CreateAbsence(absence model.Absence) (model.Absence, error)
+	UpdateAbsence(absence model.Absence) (model.Absence, error)
+	DeleteAbsence(absenceId int) error
+	GetAbsence(absenceId int) (model.Absence, error)
+	GetAbsences(query map[string][]string) ([]model.Absence, error)
+	GetAbsenceDays(userId int, query map[string][]string) ([]model.AbsenceDay, error)
+}
+
+// @Summary Get working schedule
+// @Description Retrieve the working schedule of a user
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Success 200 {object} model.Schedule "Working schedule"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "schedule not set"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /users/{user}/schedule [get]
+func (r *router) getSchedule() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.authorize(c, policy.ReadReports, userId) {
+			return
+		}
+		if !r.timeService(c).UserExists(userId) {
+			c.JSON(http.StatusBadRequest, "user not exist")
+			return
+		}
+		result, err := r.scheduleService(c).GetSchedule(userId)
+		if errors.Is(err, schedule.ErrScheduleNotFound) {
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// @Summary Set working schedule
+// @Description Create or replace the working schedule of a user. Work days are numbered from 0 (Sunday) to 6 (Saturday). Without a timezone the user's timezone is used
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Param request body model.Schedule true "Working schedule"
+// @Success 200 {object} model.Schedule "Saved schedule"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /users/{user}/schedule [put]
+func (r *router) saveSchedule() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.authorize(c, policy.ManageSchedules, userId) {
+			return
+		}
+		var body model.Schedule
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		body.UserId = userId
+		if !r.timeService(c).UserExists(userId) {
+			c.JSON(http.StatusBadRequest, "user not exist")
+			return
+		}
+		err = r.scheduleService(c).SaveSchedule(body)
+		if errors.Is(err, schedule.ErrInvalidSchedule) {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		result, err := r.scheduleService(c).GetSchedule(userId)
+		if err != nil {
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// @Summary Get overtime report
+// @Description Compares tracked time against the user's working schedule and returns daily and weekly overtime and undertime in seconds. Vacations, sick leaves and holidays are treated as non-working days
+// @Accept json
+// @Produce json
+// @Param user path int true "User ID"
+// @Param dateFrom query string false "Date From (RFC3339, local date-time or YYYY-MM-DD in the schedule's timezone)"
+// @Param dateTo query string false "Date To (RFC3339, local date-time or YYYY-MM-DD in the schedule's timezone, inclusive)"
+// @Param tz query string false "Timezone overriding the schedule's timezone"
+// @Success 200 {object} model.OvertimeReport "Overtime report"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "schedule not set"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /users/{user}/overtime [get]
+func (r *router) getOvertime() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId, err := strconv.Atoi(c.Param("user"))
+		if err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusBadRequest, "Bad request")
+			return
+		}
+		if !r.authorize(c, policy.ReadReports, userId) {
+			return
+		}
+		if !r.timeService(c).UserExists(userId) {
+			c.JSON(http.StatusBadRequest, "user not exist")
+			return
+		}
+		report, err := r.scheduleService(c).GetOvertimeReport(userId, c.Request.URL.Query())
+		switch {
+		case errors.Is(err, schedule.ErrScheduleNotFound):
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, schedule.ErrInvalidPeriod), errors.Is(err, schedule.ErrInvalidSchedule), errors.Is(err, period.ErrInvalidTimezone):
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		case err != nil:
+			logrus.Info(err)
+			c.JSON(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
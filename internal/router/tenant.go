@@ -0,0 +1,43 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+const servicesKey = "services"
+
+// Services are the services of one organization. They only see its users
+// and tasks, so a caller cannot reach another organization's data whatever
+// ids it sends.
+type Services struct {
+	Tasks     timeTrackerService
+	Schedules scheduleService
+}
+
+// TenantMiddleware stores the services of the caller's organization in the
+// context. It has to run after AuthMiddleware.
+func TenantMiddleware(tenants func(orgId int) Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgId := principalFrom(c).OrganizationId
+		if orgId == 0 {
+			unauthorized(c)
+			return
+		}
+		c.Set(servicesKey, tenants(orgId))
+		c.Next()
+	}
+}
+
+// servicesFrom panics outside TenantMiddleware rather than fall back to
+// services of no particular organization.
+func servicesFrom(c *gin.Context) Services {
+	return c.MustGet(servicesKey).(Services)
+}
+
+func (r *router) timeService(c *gin.Context) timeTrackerService {
+	return servicesFrom(c).Tasks
+}
+
+func (r *router) scheduleService(c *gin.Context) scheduleService {
+	return servicesFrom(c).Schedules
+}
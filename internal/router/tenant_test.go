@@ -0,0 +1,79 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/service/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// principalAuth authenticates every Basic login as the principal; the rest
+// of authService is left to the embedded nil interface.
+type principalAuth struct {
+	authService
+	principal model.Principal
+}
+
+func (a principalAuth) Authenticate(username, password string) (model.Principal, error) {
+	return a.principal, nil
+}
+
+func (a principalAuth) ParseAccessToken(accessToken string) (model.Principal, error) {
+	return model.Principal{}, auth.ErrInvalidToken
+}
+
+func TestTenantMiddlewareRejectsCallersWithoutOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	principals := map[string]model.Principal{
+		"admin":  {CredentialId: 1, Username: "admin", Role: model.RoleAdmin},
+		"member": {CredentialId: 2, Username: "ivan", Role: model.RoleMember},
+	}
+	for name, principal := range principals {
+		t.Run(name, func(t *testing.T) {
+			tenants := func(orgId int) Services {
+				t.Errorf("services requested for organization %d", orgId)
+				return Services{}
+			}
+			router := New(tenants, principalAuth{principal: principal})
+			for _, route := range router.ginRouter.Routes() {
+				if strings.HasPrefix(route.Path, "/auth/") || strings.HasPrefix(route.Path, "/swagger/") {
+					continue
+				}
+				path := strings.NewReplacer(":user", "1", ":session", "1", ":absence", "1", ":key", "1").Replace(route.Path)
+				req := httptest.NewRequest(route.Method, path, strings.NewReader("{}"))
+				req.SetBasicAuth("admin", "secret")
+				w := httptest.NewRecorder()
+				router.ginRouter.ServeHTTP(w, req)
+				if w.Code != http.StatusUnauthorized {
+					t.Errorf("%s %s = %d, want %d", route.Method, route.Path, w.Code, http.StatusUnauthorized)
+				}
+			}
+		})
+	}
+}
+
+func TestTenantMiddlewareScopesToCallersOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var requested []int
+	tenants := func(orgId int) Services {
+		requested = append(requested, orgId)
+		return Services{}
+	}
+	engine := gin.New()
+	engine.Use(AuthMiddleware(principalAuth{principal: model.Principal{CredentialId: 1, OrganizationId: 7, Role: model.RoleAdmin}}), TenantMiddleware(tenants))
+	engine.GET("/", func(c *gin.Context) {
+		servicesFrom(c)
+		c.Status(http.StatusNoContent)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("admin", "secret")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || len(requested) != 1 || requested[0] != 7 {
+		t.Errorf("status %d, organizations requested %v, want 204 and [7]", w.Code, requested)
+	}
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/validation"
+)
+
+// apiKeyDisplayLength is how much of a key is kept in the clear so people
+// can tell their keys apart.
+const apiKeyDisplayLength = len(model.APIKeyPrefix) + 8
+
+var (
+	ErrInvalidAPIKey    = errors.New("invalid or expired API key")
+	ErrAPIKeyManagement = errors.New("API keys cannot manage API keys")
+)
+
+// CreateAPIKey issues a key acting for the principal's login. The key itself
+// is only returned here.
+func (a *authService) CreateAPIKey(principal model.Principal, name string, scopes []string, expiresAt *time.Time) (model.APIKey, error) {
+	key := model.APIKey{CredentialId: principal.CredentialId, Name: strings.TrimSpace(name), ExpiresAt: expiresAt}
+	if principal.APIKeyId != 0 {
+		return key, ErrAPIKeyManagement
+	}
+	problems := map[string]string{}
+	if key.Name == "" || utf8.RuneCountInString(key.Name) > 100 {
+		problems["name"] = "must be 1 to 100 characters"
+	}
+	for _, scope := range scopes {
+		switch {
+		case !slices.Contains(model.Scopes, scope):
+			problems["scopes"] = "unknown scope " + scope + ", expected one of " + strings.Join(model.Scopes, ", ")
+		case scope == model.ScopeAdmin && !principal.IsAdmin():
+			problems["scopes"] = "only admins can grant " + model.ScopeAdmin
+		case !slices.Contains(key.Scopes, scope):
+			key.Scopes = append(key.Scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		problems["scopes"] = "must not be empty"
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		problems["expires_at"] = "must be in the future"
+	}
+	if len(problems) > 0 {
+		return key, &validation.Error{Message: "invalid API key", Fields: problems}
+	}
+	secret, err := randomToken()
+	if err != nil {
+		return key, err
+	}
+	secret = model.APIKeyPrefix + secret
+	key.Prefix = secret[:apiKeyDisplayLength]
+	if err := a.storage.SaveAPIKey(&key, hashToken(secret)); err != nil {
+		return key, err
+	}
+	key.Key = secret
+	return key, nil
+}
+
+// ListAPIKeys returns the keys of the principal's login.
+func (a *authService) ListAPIKeys(principal model.Principal) ([]model.APIKey, error) {
+	if principal.APIKeyId != 0 {
+		return nil, ErrAPIKeyManagement
+	}
+	return a.storage.GetAPIKeys(principal.CredentialId)
+}
+
+// DeleteAPIKey revokes a key of the principal's login; admins may revoke
+// any key of their organization.
+func (a *authService) DeleteAPIKey(principal model.Principal, id int) (bool, error) {
+	if principal.APIKeyId != 0 {
+		return false, ErrAPIKeyManagement
+	}
+	credentialId := principal.CredentialId
+	if principal.IsAdmin() {
+		credentialId = 0
+	}
+	return a.storage.DeleteAPIKey(id, credentialId, principal.OrganizationId)
+}
+
+// AuthenticateAPIKey returns the caller for an API key. The key has the
+// role of its login, except that keys of admin logins only act as admin when
+// granted the admin scope and as members otherwise.
+func (a *authService) AuthenticateAPIKey(secret string) (model.Principal, error) {
+	key, found, err := a.storage.UseAPIKey(hashToken(secret))
+	if err != nil {
+		return model.Principal{}, err
+	}
+	if !found {
+		return model.Principal{}, ErrInvalidAPIKey
+	}
+	credential, found, err := a.storage.GetCredentialById(key.CredentialId)
+	if err != nil {
+		return model.Principal{}, err
+	}
+	if !found {
+		return model.Principal{}, ErrInvalidAPIKey
+	}
+	principal := principalOf(credential)
+	if principal.Role == model.RoleAdmin && !key.HasScope(model.ScopeAdmin) {
+		principal.Role = model.RoleMember
+	}
+	principal.APIKeyId = key.Id
+	principal.Scopes = key.Scopes
+	return principal, nil
+}
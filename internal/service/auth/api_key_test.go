@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+// apiKeyStorage knows one key and the login it belongs to.
+type apiKeyStorage struct {
+	storage
+	key        model.APIKey
+	credential model.Credential
+}
+
+func (s *apiKeyStorage) UseAPIKey(hash string) (model.APIKey, bool, error) {
+	return s.key, hash == hashToken("tt_secret"), nil
+}
+
+func (s *apiKeyStorage) GetCredentialById(id int) (model.Credential, bool, error) {
+	return s.credential, id == s.credential.Id, nil
+}
+
+func TestAuthenticateAPIKeyRole(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   string
+		scopes []string
+		want   string
+	}{
+		{"admin key with admin scope", model.RoleAdmin, []string{model.ScopeAdmin, model.ScopeUsersRead}, model.RoleAdmin},
+		{"admin key without admin scope", model.RoleAdmin, []string{model.ScopeUsersRead}, model.RoleMember},
+		{"manager key", model.RoleManager, []string{model.ScopeReportsRead}, model.RoleManager},
+		{"member key with admin scope", model.RoleMember, []string{model.ScopeAdmin}, model.RoleMember},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &authService{storage: &apiKeyStorage{
+				key:        model.APIKey{Id: 4, CredentialId: 3, Scopes: tt.scopes},
+				credential: model.Credential{Id: 3, Role: tt.role, OrganizationId: model.RootOrganization},
+			}}
+			principal, err := a.AuthenticateAPIKey("tt_secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if principal.Role != tt.want || principal.APIKeyId != 4 {
+				t.Errorf("principal = %+v, want role %s", principal, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Package auth checks credentials and manages logins.
+package auth
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/validation"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrDuplicateUsername  = errors.New("username already taken")
+)
+
+type authService struct {
+	storage storage
+}
+
+type storage interface {
+	GetCredential(username string) (model.Credential, bool, error)
+	SaveCredential(credential *model.Credential) error
+	GetCredentialById(id int) (model.Credential, bool, error)
+	UserInOrganization(userId, organizationId int) bool
+	SaveRefreshToken(credentialId int, hash string, expiresAt time.Time) error
+	GetRefreshToken(hash string) (model.RefreshToken, bool, error)
+	RevokeRefreshToken(hash string) (int, bool, error)
+	LogOutRefreshToken(hash string) error
+	RevokeRefreshTokens(credentialId int) error
+	SaveOrganization(organization *model.Organization, admin *model.Credential) (bool, error)
+	GetOrganizations() ([]model.Organization, error)
+	SaveAPIKey(key *model.APIKey, hash string) error
+	GetAPIKeys(credentialId int) ([]model.APIKey, error)
+	UseAPIKey(hash string) (model.APIKey, bool, error)
+	DeleteAPIKey(id, credentialId, organizationId int) (bool, error)
+}
+
+func New(storage storage) *authService {
+	var err error
+	tokenConfig, err = TokenConfigFromEnv()
+	if err != nil {
+		logrus.Fatalf(err.Error())
+	}
+	return &authService{storage: storage}
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// Authenticate checks a username and password. Unknown usernames cost as
+// much as wrong passwords, so timing does not reveal which logins exist.
+func (a *authService) Authenticate(username, password string) (model.Principal, error) {
+	credential, found, err := a.storage.GetCredential(username)
+	if err != nil {
+		return model.Principal{}, err
+	}
+	if !found {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+		})
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return model.Principal{}, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(credential.PasswordHash), []byte(password)) != nil {
+		return model.Principal{}, ErrInvalidCredentials
+	}
+	return principalOf(credential), nil
+}
+
+func principalOf(credential model.Credential) model.Principal {
+	return model.Principal{
+		CredentialId:   credential.Id,
+		Username:       credential.Username,
+		OrganizationId: credential.OrganizationId,
+		UserId:         credential.UserId,
+		TeamId:         credential.TeamId,
+		Role:           credential.Role,
+	}
+}
+
+// CreateCredential adds a login to the organization. Member and manager
+// logins must belong to an existing user of it; a manager manages the team of
+// that user.
+func (a *authService) CreateCredential(username, password string, userId *int, role string, organizationId int) (model.Credential, error) {
+	credential, err := a.newCredential(username, password, userId, role, organizationId)
+	if err != nil {
+		return credential, err
+	}
+	return credential, a.storage.SaveCredential(&credential)
+}
+
+// newCredential validates a login and hashes its password without saving it.
+func (a *authService) newCredential(username, password string, userId *int, role string, organizationId int) (model.Credential, error) {
+	if role == "" {
+		role = model.RoleMember
+	}
+	credential := model.Credential{Username: strings.TrimSpace(username), UserId: userId, Role: role, OrganizationId: organizationId}
+	problems := map[string]string{}
+	if credential.Username == "" || strings.ContainsRune(credential.Username, ':') {
+		problems["username"] = "must be non-empty and not contain ':'"
+	}
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		problems["password"] = "must be at least 8 characters"
+	} else if len(password) > 72 {
+		problems["password"] = "must be at most 72 bytes"
+	}
+	if userId != nil && !a.storage.UserInOrganization(*userId, organizationId) {
+		problems["user_id"] = "user not exist"
+	}
+	if !slices.Contains(model.Roles, role) {
+		problems["role"] = "must be one of " + strings.Join(model.Roles, ", ")
+	} else if userId == nil && role != model.RoleAdmin {
+		problems["user_id"] = "required for non-admin credentials"
+	}
+	if len(problems) > 0 {
+		return credential, &validation.Error{Message: "invalid credential", Fields: problems}
+	}
+	_, found, err := a.storage.GetCredential(credential.Username)
+	if err != nil {
+		return credential, err
+	}
+	if found {
+		return credential, ErrDuplicateUsername
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return credential, err
+	}
+	credential.PasswordHash = string(hash)
+	return credential, nil
+}
+
+// Bootstrap creates the admin login from AUTH_ADMIN_USERNAME and
+// AUTH_ADMIN_PASSWORD in the root organization unless it already exists, so
+// that a fresh installation can be logged into. The password has no default:
+// with a username set it must be provided at deploy time.
+func (a *authService) Bootstrap() error {
+	username := os.Getenv("AUTH_ADMIN_USERNAME")
+	password := os.Getenv("AUTH_ADMIN_PASSWORD")
+	if username == "" {
+		return nil
+	}
+	if password == "" {
+		return errors.New("AUTH_ADMIN_PASSWORD must be set when AUTH_ADMIN_USERNAME is")
+	}
+	_, err := a.CreateCredential(username, password, nil, model.RoleAdmin, model.RootOrganization)
+	if errors.Is(err, ErrDuplicateUsername) {
+		return nil
+	}
+	if err == nil {
+		logrus.Infof("created admin login %q", username)
+	}
+	return err
+}
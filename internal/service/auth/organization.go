@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/validation"
+)
+
+var ErrDuplicateOrganization = errors.New("organization already exists")
+
+// CreateOrganization creates an organization with its first admin login,
+// which then adds the organization's users, teams and other logins.
+func (a *authService) CreateOrganization(name, username, password string) (model.Organization, model.Credential, error) {
+	organization := model.Organization{Name: strings.TrimSpace(name)}
+	if organization.Name == "" || utf8.RuneCountInString(organization.Name) > 100 {
+		return organization, model.Credential{}, &validation.Error{
+			Message: "invalid organization",
+			Fields:  map[string]string{"name": "must be 1 to 100 characters"},
+		}
+	}
+	admin, err := a.newCredential(username, password, nil, model.RoleAdmin, 0)
+	if err != nil {
+		return organization, admin, err
+	}
+	created, err := a.storage.SaveOrganization(&organization, &admin)
+	if err != nil {
+		return organization, admin, err
+	}
+	if !created {
+		return organization, admin, ErrDuplicateOrganization
+	}
+	return organization, admin, nil
+}
+
+func (a *authService) GetOrganizations() ([]model.Organization, error) {
+	return a.storage.GetOrganizations()
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/token"
+	"github.com/sirupsen/logrus"
+)
+
+const minSecretLength = 32
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// TokenConfig holds the HS256 secret and the lifetimes of access and refresh
+// tokens.
+type TokenConfig struct {
+	Secret     []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+var tokenConfig = TokenConfig{
+	AccessTTL:  15 * time.Minute,
+	RefreshTTL: 30 * 24 * time.Hour,
+}
+
+// TokenConfigFromEnv reads JWT_SECRET (at least 32 bytes, e.g. from
+// `openssl rand -base64 48`), JWT_ACCESS_TTL and JWT_REFRESH_TTL (Go
+// durations, 15m and 720h by default). There is no default secret: the
+// server does not start without one.
+func TokenConfigFromEnv() (TokenConfig, error) {
+	config := tokenConfig
+	config.Secret = []byte(os.Getenv("JWT_SECRET"))
+	if len(config.Secret) < minSecretLength {
+		return config, fmt.Errorf("JWT_SECRET must be at least %d bytes", minSecretLength)
+	}
+	for key, target := range map[string]*time.Duration{
+		"JWT_ACCESS_TTL":  &config.AccessTTL,
+		"JWT_REFRESH_TTL": &config.RefreshTTL,
+	} {
+		if val := os.Getenv(key); val != "" {
+			d, err := time.ParseDuration(val)
+			if err != nil || d <= 0 {
+				return config, fmt.Errorf("%s must be a positive duration, got %q", key, val)
+			}
+			*target = d
+		}
+	}
+	return config, nil
+}
+
+// Login checks the credentials and issues a token pair.
+func (a *authService) Login(username, password string) (model.TokenPair, error) {
+	principal, err := a.Authenticate(username, password)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	return a.issue(principal)
+}
+
+// Refresh exchanges a refresh token for a new pair. Every refresh token can
+// be used once: presenting one that was already exchanged means it leaked,
+// so all refresh tokens of that login are revoked. A token revoked by logout
+// is merely rejected.
+func (a *authService) Refresh(refreshToken string) (model.TokenPair, error) {
+	hash := hashToken(refreshToken)
+	credentialId, ok, err := a.storage.RevokeRefreshToken(hash)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	if !ok {
+		stored, found, err := a.storage.GetRefreshToken(hash)
+		if err != nil {
+			return model.TokenPair{}, err
+		}
+		if found && stored.RevokedAt != nil && !stored.LoggedOut && stored.ExpiresAt.After(time.Now()) {
+			logrus.Warnf("refresh token reused for credential %d, revoking its sessions", stored.CredentialId)
+			if err := a.storage.RevokeRefreshTokens(stored.CredentialId); err != nil {
+				return model.TokenPair{}, err
+			}
+		}
+		return model.TokenPair{}, ErrInvalidToken
+	}
+	credential, found, err := a.storage.GetCredentialById(credentialId)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	if !found {
+		return model.TokenPair{}, ErrInvalidToken
+	}
+	return a.issue(principalOf(credential))
+}
+
+// Logout revokes the refresh token. Access tokens stay valid until they
+// expire.
+func (a *authService) Logout(refreshToken string) error {
+	return a.storage.LogOutRefreshToken(hashToken(refreshToken))
+}
+
+// ParseAccessToken verifies an access token and returns its caller.
+func (a *authService) ParseAccessToken(accessToken string) (model.Principal, error) {
+	claims, err := token.Verify(accessToken, tokenConfig.Secret, time.Now())
+	if err != nil {
+		return model.Principal{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	// Tokens issued before organizations existed carry none and would not
+	// be limited to any.
+	if claims.OrganizationId == 0 {
+		return model.Principal{}, ErrInvalidToken
+	}
+	principal := model.Principal{
+		CredentialId:   claims.CredentialId,
+		Username:       claims.Username,
+		OrganizationId: claims.OrganizationId,
+		TeamId:         claims.TeamId,
+		Role:           claims.Role,
+	}
+	if claims.Subject != "" {
+		userId, err := strconv.Atoi(claims.Subject)
+		if err != nil {
+			return model.Principal{}, ErrInvalidToken
+		}
+		principal.UserId = &userId
+	}
+	return principal, nil
+}
+
+// issue signs an access token for the principal, its user as the subject,
+// and stores a new refresh token.
+func (a *authService) issue(principal model.Principal) (model.TokenPair, error) {
+	now := time.Now()
+	claims := token.Claims{
+		CredentialId:   principal.CredentialId,
+		Username:       principal.Username,
+		OrganizationId: principal.OrganizationId,
+		TeamId:         principal.TeamId,
+		Role:           principal.Role,
+		IssuedAt:       now.Unix(),
+		ExpiresAt:      now.Add(tokenConfig.AccessTTL).Unix(),
+	}
+	if principal.UserId != nil {
+		claims.Subject = strconv.Itoa(*principal.UserId)
+	}
+	accessToken, err := token.Sign(claims, tokenConfig.Secret)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	refreshToken, err := randomToken()
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	err = a.storage.SaveRefreshToken(principal.CredentialId, hashToken(refreshToken), now.Add(tokenConfig.RefreshTTL))
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	return model.TokenPair{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(tokenConfig.AccessTTL.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func randomToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashToken is how refresh tokens are stored: they are random, so an
+// unsalted SHA-256 is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
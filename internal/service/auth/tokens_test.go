@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+// tokenStorage keeps refresh tokens in memory; the methods tokens do not use
+// are left to the embedded nil interface.
+type tokenStorage struct {
+	storage
+	tokens map[string]*model.RefreshToken
+}
+
+func (s *tokenStorage) GetCredentialById(id int) (model.Credential, bool, error) {
+	return model.Credential{Id: id, Username: "ivan", Role: model.RoleMember, OrganizationId: model.RootOrganization}, true, nil
+}
+
+func (s *tokenStorage) SaveRefreshToken(credentialId int, hash string, expiresAt time.Time) error {
+	s.tokens[hash] = &model.RefreshToken{Id: len(s.tokens) + 1, CredentialId: credentialId, ExpiresAt: expiresAt}
+	return nil
+}
+
+func (s *tokenStorage) GetRefreshToken(hash string) (model.RefreshToken, bool, error) {
+	token, ok := s.tokens[hash]
+	if !ok {
+		return model.RefreshToken{}, false, nil
+	}
+	return *token, true, nil
+}
+
+func (s *tokenStorage) RevokeRefreshToken(hash string) (int, bool, error) {
+	token, ok := s.tokens[hash]
+	if !ok || token.RevokedAt != nil || !token.ExpiresAt.After(time.Now()) {
+		return 0, false, nil
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	return token.CredentialId, true, nil
+}
+
+func (s *tokenStorage) LogOutRefreshToken(hash string) error {
+	if token, ok := s.tokens[hash]; ok && token.RevokedAt == nil {
+		now := time.Now()
+		token.RevokedAt, token.LoggedOut = &now, true
+	}
+	return nil
+}
+
+func (s *tokenStorage) RevokeRefreshTokens(credentialId int) error {
+	now := time.Now()
+	for _, token := range s.tokens {
+		if token.CredentialId == credentialId && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func newTokenService(t *testing.T) (*authService, *tokenStorage) {
+	t.Helper()
+	saved := tokenConfig
+	t.Cleanup(func() { tokenConfig = saved })
+	tokenConfig.Secret = []byte("0123456789abcdef0123456789abcdef")
+	storage := &tokenStorage{tokens: map[string]*model.RefreshToken{}}
+	return &authService{storage: storage}, storage
+}
+
+func TestRefreshReuseRevokesSessions(t *testing.T) {
+	a, _ := newTokenService(t)
+	principal := model.Principal{CredentialId: 3, OrganizationId: model.RootOrganization, Role: model.RoleMember}
+	first, err := a.issue(principal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := a.issue(principal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := a.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.Refresh(first.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("reused token: err = %v, want %v", err, ErrInvalidToken)
+	}
+	for name, pair := range map[string]model.TokenPair{"rotated": rotated, "other session": other} {
+		if _, err := a.Refresh(pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want the session revoked after reuse", name, err)
+		}
+	}
+}
+
+func TestRefreshAfterLogout(t *testing.T) {
+	a, _ := newTokenService(t)
+	principal := model.Principal{CredentialId: 3, OrganizationId: model.RootOrganization, Role: model.RoleMember}
+	loggedOut, err := a.issue(principal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := a.issue(principal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Logout(loggedOut.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.Refresh(loggedOut.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("logged out token: err = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := a.Refresh(other.RefreshToken); err != nil {
+		t.Errorf("other session: err = %v, want it to survive the logout", err)
+	}
+}
+
+func TestParseAccessToken(t *testing.T) {
+	a, _ := newTokenService(t)
+	userId := 7
+	pair, err := a.issue(model.Principal{CredentialId: 3, UserId: &userId, Username: "ivan", OrganizationId: 2, Role: model.RoleMember})
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, err := a.ParseAccessToken(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.CredentialId != 3 || principal.UserId == nil || *principal.UserId != 7 || principal.OrganizationId != 2 {
+		t.Errorf("principal = %+v", principal)
+	}
+	if _, err := a.ParseAccessToken(pair.AccessToken + "x"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("tampered token: err = %v, want %v", err, ErrInvalidToken)
+	}
+	orgless, err := a.issue(model.Principal{CredentialId: 3, Username: "ivan", Role: model.RoleAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ParseAccessToken(orgless.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token without organization: err = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestTokenConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name, secret, accessTTL string
+		ok                      bool
+	}{
+		{"missing secret", "", "", false},
+		{"short secret", "0123456789abcdef0123456789abcde", "", false},
+		{"bad ttl", "0123456789abcdef0123456789abcdef", "soon", false},
+		{"negative ttl", "0123456789abcdef0123456789abcdef", "-1m", false},
+		{"valid", "0123456789abcdef0123456789abcdef", "5m", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", tt.secret)
+			t.Setenv("JWT_ACCESS_TTL", tt.accessTTL)
+			t.Setenv("JWT_REFRESH_TTL", "")
+			config, err := TokenConfigFromEnv()
+			if (err == nil) != tt.ok {
+				t.Fatalf("TokenConfigFromEnv() error = %v", err)
+			}
+			if tt.ok && config.AccessTTL != 5*time.Minute {
+				t.Errorf("AccessTTL = %s", config.AccessTTL)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+// Package enrichment fills in pending users from the people API in the
+// background.
+package enrichment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/client/people"
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/passport"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	EventEnriched = "user.enriched"
+	EventFailed   = "user.enrichment_failed"
+)
+
+var ErrQueueFull = errors.New("enrichment queue is full")
+
+type Config struct {
+	Workers     int
+	QueueSize   int
+	MaxAttempts int
+	Backoff     time.Duration
+	WebhookURL  string
+}
+
+// Event is posted to the webhook when a pending user is resolved.
+type Event struct {
+	Event string     `json:"event"`
+	User  model.User `json:"user"`
+}
+
+type storage interface {
+	GetUser(userId int) (model.User, error)
+	GetUsersByStatus(status string) ([]model.User, error)
+	EnrichUser(user model.User) (bool, error)
+	FailPendingUser(userId int) (bool, error)
+}
+
+type peopleClient interface {
+	GetInfo(ctx context.Context, series, number string) (people.Person, error)
+}
+
+type pool struct {
+	storage storage
+	people  peopleClient
+	config  Config
+	jobs    chan int
+	webhook *http.Client
+}
+
+// ConfigFromEnv reads ENRICHMENT_WORKERS, ENRICHMENT_QUEUE_SIZE,
+// ENRICHMENT_MAX_ATTEMPTS, ENRICHMENT_BACKOFF and ENRICHMENT_WEBHOOK_URL.
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		Workers:     4,
+		QueueSize:   1000,
+		MaxAttempts: 5,
+		Backoff:     2 * time.Second,
+		WebhookURL:  os.Getenv("ENRICHMENT_WEBHOOK_URL"),
+	}
+	ints := map[string]*int{
+		"ENRICHMENT_WORKERS":      &config.Workers,
+		"ENRICHMENT_QUEUE_SIZE":   &config.QueueSize,
+		"ENRICHMENT_MAX_ATTEMPTS": &config.MaxAttempts,
+	}
+	for key, target := range ints {
+		if val := os.Getenv(key); val != "" {
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return config, fmt.Errorf("%s: must be a positive number", key)
+			}
+			*target = n
+		}
+	}
+	if val := os.Getenv("ENRICHMENT_BACKOFF"); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return config, fmt.Errorf("ENRICHMENT_BACKOFF: %w", err)
+		}
+		config.Backoff = d
+	}
+	return config, nil
+}
+
+func New(storage storage, people peopleClient, config Config) *pool {
+	return &pool{
+		storage: storage,
+		people:  people,
+		config:  config,
+		jobs:    make(chan int, config.QueueSize),
+		webhook: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Start launches the workers and re-queues users left pending by a previous
+// run.
+func (p *pool) Start() {
+	for i := 0; i < p.config.Workers; i++ {
+		go p.work()
+	}
+	pending, err := p.storage.GetUsersByStatus(model.UserPending)
+	if err != nil {
+		logrus.Info(err)
+		return
+	}
+	go func() {
+		for _, user := range pending {
+			p.jobs <- user.Id
+		}
+	}()
+}
+
+// Enqueue schedules enrichment of a pending user without blocking, and
+// returns ErrQueueFull when the queue has no room.
+func (p *pool) Enqueue(userId int) error {
+	select {
+	case p.jobs <- userId:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (p *pool) work() {
+	for userId := range p.jobs {
+		p.enrich(userId)
+	}
+}
+
+func (p *pool) enrich(userId int) {
+	user, err := p.storage.GetUser(userId)
+	if err != nil {
+		logrus.Info(err)
+		return
+	}
+	if user.Status != model.UserPending {
+		return
+	}
+	parsed, err := passport.Parse(user.PassportNumber)
+	if err != nil {
+		p.fail(user, err)
+		return
+	}
+	var person people.Person
+	for attempt := 1; attempt <= p.config.MaxAttempts; attempt++ {
+		person, err = p.people.GetInfo(context.Background(), parsed.Series, parsed.Number)
+		if !retryable(err) || attempt == p.config.MaxAttempts {
+			break
+		}
+		logrus.Debugf("enrichment of user %d failed, attempt %d: %s", userId, attempt, err.Error())
+		time.Sleep(p.config.Backoff << (attempt - 1))
+	}
+	if err != nil {
+		p.fail(user, err)
+		return
+	}
+	user.Name = person.Name
+	user.Surname = person.Surname
+	user.Patronymic = person.Patronymic
+	user.Address = person.Address
+	user.Status = model.UserActive
+	// The lookup may have taken a while: an edit, anonymization or deletion
+	// in the meantime wins over the looked-up profile.
+	enriched, err := p.storage.EnrichUser(user)
+	if err != nil {
+		logrus.Info(err)
+		return
+	}
+	if !enriched {
+		logrus.Debugf("enrichment of user %d superseded", userId)
+		return
+	}
+	p.notify(EventEnriched, user)
+}
+
+func (p *pool) fail(user model.User, cause error) {
+	logrus.Infof("enrichment of user %d failed: %s", user.Id, cause.Error())
+	failed, err := p.storage.FailPendingUser(user.Id)
+	if err != nil {
+		logrus.Info(err)
+		return
+	}
+	if !failed {
+		logrus.Debugf("enrichment of user %d superseded", user.Id)
+		return
+	}
+	user.Status = model.UserFailed
+	p.notify(EventFailed, user)
+}
+
+func (p *pool) notify(event string, user model.User) {
+	if p.config.WebhookURL == "" {
+		return
+	}
+	body, err := json.Marshal(Event{Event: event, User: user})
+	if err != nil {
+		logrus.Info(err)
+		return
+	}
+	resp, err := p.webhook.Post(p.config.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logrus.Infof("webhook for user %d failed: %s", user.Id, err.Error())
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		logrus.Infof("webhook for user %d returned status %d", user.Id, resp.StatusCode)
+	}
+}
+
+func retryable(err error) bool {
+	return errors.Is(err, people.ErrUnavailable) || errors.Is(err, people.ErrTimeout) || errors.Is(err, people.ErrCircuitOpen)
+}
@@ -0,0 +1,165 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/client/people"
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+// fakeStorage keeps one user and applies the same guards as the database.
+type fakeStorage struct {
+	mu   sync.Mutex
+	user model.User
+}
+
+func (s *fakeStorage) GetUser(userId int) (model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.user, nil
+}
+
+func (s *fakeStorage) GetUsersByStatus(status string) ([]model.User, error) {
+	return nil, nil
+}
+
+func (s *fakeStorage) EnrichUser(user model.User) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.user.Status != model.UserPending || s.user.AnonymizedAt != nil || s.user.DeletedAt != nil {
+		return false, nil
+	}
+	s.user.Name, s.user.Surname, s.user.Patronymic, s.user.Address = user.Name, user.Surname, user.Patronymic, user.Address
+	s.user.Status = model.UserActive
+	return true, nil
+}
+
+func (s *fakeStorage) FailPendingUser(userId int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.user.Status != model.UserPending || s.user.DeletedAt != nil {
+		return false, nil
+	}
+	s.user.Status = model.UserFailed
+	return true, nil
+}
+
+func (s *fakeStorage) anonymize() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.user.Name, s.user.Surname, s.user.Patronymic, s.user.Address = "", "", "", ""
+	s.user.AnonymizedAt = &now
+}
+
+// edit changes the user like UpdateUser and PatchUser do, which activate a
+// pending user.
+func (s *fakeStorage) edit(name, surname string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user.Name, s.user.Surname = name, surname
+	s.user.Status = model.UserActive
+}
+
+func (s *fakeStorage) delete() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.user.DeletedAt = &now
+}
+
+// blockingPeople answers a lookup only once released.
+type blockingPeople struct {
+	called  chan struct{}
+	release chan struct{}
+}
+
+func (p blockingPeople) GetInfo(ctx context.Context, series, number string) (people.Person, error) {
+	close(p.called)
+	<-p.release
+	return people.Person{Name: "Ivan", Surname: "Ivanov", Address: "Moscow"}, nil
+}
+
+// TestChangedDuringLookup changes the user while its lookup is in flight:
+// the change wins and no event is sent.
+func TestChangedDuringLookup(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(s *fakeStorage)
+		check  func(t *testing.T, user model.User)
+	}{
+		{"anonymized", (*fakeStorage).anonymize, func(t *testing.T, user model.User) {
+			if user.Name != "" || user.Surname != "" || user.Address != "" || user.AnonymizedAt == nil {
+				t.Errorf("anonymized user was filled in again: %+v", user)
+			}
+		}},
+		{"edited", func(s *fakeStorage) { s.edit("Petr", "Petrov") }, func(t *testing.T, user model.User) {
+			if user.Name != "Petr" || user.Surname != "Petrov" || user.Address != "" {
+				t.Errorf("edit was overwritten: %+v", user)
+			}
+		}},
+		{"deleted", (*fakeStorage).delete, func(t *testing.T, user model.User) {
+			if user.Status != model.UserPending || user.Surname != "" {
+				t.Errorf("deleted user was enriched: %+v", user)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu     sync.Mutex
+				events []string
+			)
+			webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var event Event
+				json.NewDecoder(r.Body).Decode(&event)
+				mu.Lock()
+				events = append(events, event.Event)
+				mu.Unlock()
+			}))
+			defer webhook.Close()
+
+			storage := &fakeStorage{user: model.User{Id: 1, PassportNumber: "1234 567890", Status: model.UserPending}}
+			lookup := blockingPeople{called: make(chan struct{}), release: make(chan struct{})}
+			p := New(storage, lookup, Config{Workers: 1, QueueSize: 1, MaxAttempts: 1, WebhookURL: webhook.URL})
+
+			done := make(chan struct{})
+			go func() {
+				p.enrich(1)
+				close(done)
+			}()
+			<-lookup.called
+			tt.change(storage)
+			close(lookup.release)
+			<-done
+
+			user, _ := storage.GetUser(1)
+			tt.check(t, user)
+			mu.Lock()
+			defer mu.Unlock()
+			if len(events) != 0 {
+				t.Errorf("events = %v, want none", events)
+			}
+		})
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	storage := &fakeStorage{user: model.User{Id: 1, PassportNumber: "1234 567890", Status: model.UserPending}}
+	lookup := blockingPeople{called: make(chan struct{}), release: make(chan struct{})}
+	close(lookup.release)
+	p := New(storage, lookup, Config{Workers: 1, QueueSize: 1, MaxAttempts: 1})
+
+	p.enrich(1)
+
+	user, _ := storage.GetUser(1)
+	if user.Status != model.UserActive || user.Surname != "Ivanov" || user.Address != "Moscow" {
+		t.Errorf("user = %+v", user)
+	}
+}
@@ -0,0 +1,166 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/period"
+)
+
+var (
+	ErrAbsenceNotFound = errors.New("absence not exist")
+	ErrInvalidAbsence  = errors.New("invalid absence")
+)
+
+var absenceKinds = map[string]bool{
+	model.AbsenceVacation:  true,
+	model.AbsenceSickLeave: true,
+	model.AbsenceHoliday:   true,
+}
+
+func (s *scheduleService) CreateAbsence(absence model.Absence) (model.Absence, error) {
+	if err := s.validateAbsence(absence); err != nil {
+		return absence, err
+	}
+	err := s.storage.SaveAbsence(&absence)
+	return absence, err
+}
+
+func (s *scheduleService) UpdateAbsence(absence model.Absence) (model.Absence, error) {
+	if !s.storage.AbsenceExists(absence.Id) {
+		return absence, ErrAbsenceNotFound
+	}
+	if err := s.validateAbsence(absence); err != nil {
+		return absence, err
+	}
+	return absence, s.storage.UpdateAbsence(absence)
+}
+
+func (s *scheduleService) DeleteAbsence(absenceId int) error {
+	if !s.storage.AbsenceExists(absenceId) {
+		return ErrAbsenceNotFound
+	}
+	return s.storage.DeleteAbsence(absenceId)
+}
+
+func (s *scheduleService) GetAbsence(absenceId int) (model.Absence, error) {
+	if !s.storage.AbsenceExists(absenceId) {
+		return model.Absence{}, ErrAbsenceNotFound
+	}
+	return s.storage.GetAbsence(absenceId)
+}
+
+func (s *scheduleService) GetAbsences(query map[string][]string) ([]model.Absence, error) {
+	for _, key := range []string{"userId", "teamId"} {
+		if val, ok := query[key]; ok {
+			if _, err := strconv.Atoi(val[0]); err != nil {
+				return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidAbsence, key)
+			}
+		}
+	}
+	if val, ok := query["kind"]; ok && !absenceKinds[val[0]] {
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidAbsence, val[0])
+	}
+	for _, key := range []string{"dateFrom", "dateTo"} {
+		if val, ok := query[key]; ok {
+			if _, err := time.Parse(dateLayout, val[0]); err != nil {
+				return nil, fmt.Errorf("%w: %s must be in YYYY-MM-DD format", ErrInvalidAbsence, key)
+			}
+		}
+	}
+	return s.storage.GetAbsences(query)
+}
+
+// GetAbsenceDays lists the user's absence days within dateFrom and dateTo,
+// interpreted in the user's timezone unless the tz parameter overrides it.
+func (s *scheduleService) GetAbsenceDays(userId int, query map[string][]string) ([]model.AbsenceDay, error) {
+	user, err := s.storage.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := period.Location(query, user.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	region := ""
+	if s.storage.ScheduleExists(userId) {
+		schedule, err := s.storage.GetSchedule(userId)
+		if err != nil {
+			return nil, err
+		}
+		region = schedule.Region
+	}
+	from := time.Date(1970, 1, 1, 0, 0, 0, 0, loc)
+	to := time.Date(9999, 12, 31, 0, 0, 0, 0, loc)
+	if val, ok := query["dateFrom"]; ok {
+		if t, err := period.Parse(val[0], loc, false); err == nil {
+			from = period.StartOfDay(t.In(loc))
+		}
+	}
+	if val, ok := query["dateTo"]; ok {
+		if t, err := period.Parse(val[0], loc, true); err == nil {
+			to = period.StartOfDay(t.In(loc).Add(-time.Nanosecond))
+		}
+	}
+	absences, err := s.storage.GetAbsencesForUser(userId, region, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return expandAbsences(absences, from, to), nil
+}
+
+func (s *scheduleService) validateAbsence(absence model.Absence) error {
+	if !absenceKinds[absence.Kind] {
+		return fmt.Errorf("%w: kind must be one of vacation, sick_leave, holiday", ErrInvalidAbsence)
+	}
+	if absence.UserId == nil && absence.Kind != model.AbsenceHoliday {
+		return fmt.Errorf("%w: user_id is required for %s", ErrInvalidAbsence, absence.Kind)
+	}
+	if absence.UserId != nil && !s.storage.UserExists(*absence.UserId) {
+		return fmt.Errorf("%w: user not exist", ErrInvalidAbsence)
+	}
+	from, err := time.Parse(dateLayout, absence.DateFrom)
+	if err != nil {
+		return fmt.Errorf("%w: date_from must be in YYYY-MM-DD format", ErrInvalidAbsence)
+	}
+	to, err := time.Parse(dateLayout, absence.DateTo)
+	if err != nil {
+		return fmt.Errorf("%w: date_to must be in YYYY-MM-DD format", ErrInvalidAbsence)
+	}
+	if to.Before(from) {
+		return fmt.Errorf("%w: date_from must not be after date_to", ErrInvalidAbsence)
+	}
+	return nil
+}
+
+// expandAbsences turns absence records into one entry per calendar day within
+// [from, to]. When records overlap, the one listed first wins.
+func expandAbsences(absences []model.Absence, from, to time.Time) []model.AbsenceDay {
+	days := []model.AbsenceDay{}
+	seen := map[string]bool{}
+	first, last := from.Format(dateLayout), to.Format(dateLayout)
+	for _, absence := range absences {
+		start, err := time.Parse(dateLayout, absence.DateFrom)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(dateLayout, absence.DateTo)
+		if err != nil {
+			continue
+		}
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			date := day.Format(dateLayout)
+			if date < first || date > last || seen[date] {
+				continue
+			}
+			seen[date] = true
+			days = append(days, model.AbsenceDay{Date: date, Kind: absence.Kind, AbsenceId: absence.Id})
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days
+}
@@ -0,0 +1,183 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/period"
+)
+
+const (
+	dateLayout     = period.DateLayout
+	maxReportDays  = 366
+	defaultPeriod  = 7
+	secondsPerHour = 3600
+)
+
+// parsePeriod reads dateFrom/dateTo as RFC3339 timestamps, local date-times
+// or calendar dates in loc. A calendar dateTo is inclusive. Without parameters
+// the last seven days including today are reported.
+func parsePeriod(query map[string][]string, loc *time.Location, now time.Time) (time.Time, time.Time, error) {
+	to := period.StartOfDay(now.In(loc)).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -defaultPeriod)
+	if val, ok := query["dateFrom"]; ok {
+		t, err := period.Parse(val[0], loc, false)
+		if err != nil {
+			return from, to, fmt.Errorf("%w: dateFrom must be RFC3339 or YYYY-MM-DD", ErrInvalidPeriod)
+		}
+		from = t
+		if _, ok := query["dateTo"]; !ok {
+			to = period.StartOfDay(from.In(loc)).AddDate(0, 0, defaultPeriod)
+		}
+	}
+	if val, ok := query["dateTo"]; ok {
+		t, err := period.Parse(val[0], loc, true)
+		if err != nil {
+			return from, to, fmt.Errorf("%w: dateTo must be RFC3339 or YYYY-MM-DD", ErrInvalidPeriod)
+		}
+		to = t
+	}
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("%w: dateFrom must be before dateTo", ErrInvalidPeriod)
+	}
+	if to.Sub(from) > maxReportDays*24*time.Hour {
+		return from, to, fmt.Errorf("%w: period must not exceed %d days", ErrInvalidPeriod, maxReportDays)
+	}
+	return from.In(loc), to.In(loc), nil
+}
+
+// overlap returns the number of seconds [aStart, aEnd) and [bStart, bEnd) share.
+func overlap(aStart, aEnd, bStart, bEnd time.Time) int {
+	start, end := aStart, aEnd
+	if bStart.After(start) {
+		start = bStart
+	}
+	if bEnd.Before(end) {
+		end = bEnd
+	}
+	if !start.Before(end) {
+		return 0
+	}
+	return int(end.Sub(start).Seconds())
+}
+
+func atClock(day time.Time, clock string) time.Time {
+	t, _ := time.Parse("15:04", clock)
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location())
+}
+
+type periodTotals struct {
+	tracked  int
+	expected int
+	outside  int
+}
+
+func (e *periodTotals) entry(date string) model.OvertimeEntry {
+	entry := model.OvertimeEntry{
+		Date:         date,
+		Tracked:      e.tracked,
+		Expected:     e.expected,
+		OutsideHours: e.outside,
+	}
+	if e.tracked > e.expected {
+		entry.Overtime = e.tracked - e.expected
+	} else {
+		entry.Undertime = e.expected - e.tracked
+	}
+	return entry
+}
+
+func (e *periodTotals) add(other periodTotals) {
+	e.tracked += other.tracked
+	e.expected += other.expected
+	e.outside += other.outside
+}
+
+// buildReport attributes session time to calendar days in loc. Absence days
+// are treated as non-working: nothing is expected and all tracked time counts
+// as outside working hours.
+func buildReport(schedule model.Schedule, sessions []model.Session, absences []model.AbsenceDay, from, to time.Time, loc *time.Location, now time.Time) model.OvertimeReport {
+	report := model.OvertimeReport{
+		DateFrom: from,
+		DateTo:   to,
+		Timezone: loc.String(),
+		Days:     []model.OvertimeEntry{},
+		Weeks:    []model.OvertimeEntry{},
+	}
+	workDays := map[time.Weekday]bool{}
+	for _, day := range schedule.WorkDays {
+		workDays[time.Weekday(day)] = true
+	}
+	absenceByDate := map[string]string{}
+	for _, absence := range absences {
+		absenceByDate[absence.Date] = absence.Kind
+	}
+	dailyExpected := 0
+	if len(workDays) > 0 {
+		dailyExpected = schedule.WeeklyHours * secondsPerHour / len(workDays)
+	}
+
+	var total, week periodTotals
+	var currentWeek time.Time
+	for day := period.StartOfDay(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		dayFrom, dayTo := day, day.AddDate(0, 0, 1)
+		if from.After(dayFrom) {
+			dayFrom = from
+		}
+		if to.Before(dayTo) {
+			dayTo = to
+		}
+		totals := periodTotals{}
+		absence := absenceByDate[day.Format(dateLayout)]
+		isWorkDay := workDays[day.Weekday()] && absence == ""
+		if isWorkDay {
+			totals.expected = dailyExpected
+		}
+		inside := 0
+		for _, session := range sessions {
+			end := now
+			if session.StoppedAt != nil {
+				end = *session.StoppedAt
+			}
+			totals.tracked += overlap(session.StartedAt, end, dayFrom, dayTo)
+			if isWorkDay {
+				workFrom, workTo := atClock(day, schedule.DayStart), atClock(day, schedule.DayEnd)
+				inside += overlap(session.StartedAt, end, maxTime(dayFrom, workFrom), minTime(dayTo, workTo))
+			}
+		}
+		totals.outside = totals.tracked - inside
+
+		weekStart := period.StartOfWeek(day)
+		if !weekStart.Equal(currentWeek) {
+			if !currentWeek.IsZero() {
+				report.Weeks = append(report.Weeks, week.entry(currentWeek.Format(dateLayout)))
+			}
+			currentWeek, week = weekStart, periodTotals{}
+		}
+		week.add(totals)
+		total.add(totals)
+		entry := totals.entry(day.Format(dateLayout))
+		entry.Absence = absence
+		report.Days = append(report.Days, entry)
+	}
+	if !currentWeek.IsZero() {
+		report.Weeks = append(report.Weeks, week.entry(currentWeek.Format(dateLayout)))
+	}
+	report.Total = total.entry(from.Format(dateLayout))
+	return report
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,290 @@
+package schedule
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestParsePeriod(t *testing.T) {
+	now := time.Date(2024, 7, 10, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		tz       string
+		query    map[string][]string
+		wantFrom string
+		wantTo   string
+	}{
+		{"default is the last seven days", "UTC", nil, "2024-07-04T00:00:00Z", "2024-07-11T00:00:00Z"},
+		{"default in the timezone", "Asia/Tokyo", nil, "2024-07-05T00:00:00+09:00", "2024-07-12T00:00:00+09:00"},
+		{"week", "UTC", map[string][]string{"dateFrom": {"2024-07-01"}, "dateTo": {"2024-07-07"}}, "2024-07-01T00:00:00Z", "2024-07-08T00:00:00Z"},
+		{"month", "UTC", map[string][]string{"dateFrom": {"2024-02-01"}, "dateTo": {"2024-02-29"}}, "2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z"},
+		{"dateFrom alone spans a week", "UTC", map[string][]string{"dateFrom": {"2024-07-01"}}, "2024-07-01T00:00:00Z", "2024-07-08T00:00:00Z"},
+		{"leap year", "UTC", map[string][]string{"dateFrom": {"2024-01-01"}, "dateTo": {"2024-12-31"}}, "2024-01-01T00:00:00Z", "2025-01-01T00:00:00Z"},
+		{"week into summer time", "Europe/Berlin", map[string][]string{"dateFrom": {"2024-03-25"}, "dateTo": {"2024-03-31"}}, "2024-03-25T00:00:00+01:00", "2024-04-01T00:00:00+02:00"},
+		{"month out of summer time", "Europe/Berlin", map[string][]string{"dateFrom": {"2024-10-01"}, "dateTo": {"2024-10-31"}}, "2024-10-01T00:00:00+02:00", "2024-11-01T00:00:00+01:00"},
+		{"local date-times", "Asia/Tokyo", map[string][]string{"dateFrom": {"2024-07-01T09:00:00"}, "dateTo": {"2024-07-01T18:00:00"}}, "2024-07-01T09:00:00+09:00", "2024-07-01T18:00:00+09:00"},
+		{"offset wins over the timezone", "Europe/Berlin", map[string][]string{"dateFrom": {"2024-07-01T00:00:00+03:00"}, "dateTo": {"2024-07-01"}}, "2024-06-30T23:00:00+02:00", "2024-07-02T00:00:00+02:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLocation(t, tt.tz)
+			from, to, err := parsePeriod(tt.query, loc, now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !from.Equal(mustTime(t, tt.wantFrom)) || !to.Equal(mustTime(t, tt.wantTo)) {
+				t.Errorf("period = %s - %s, want %s - %s", from.Format(time.RFC3339), to.Format(time.RFC3339), tt.wantFrom, tt.wantTo)
+			}
+			if from.Location() != loc || to.Location() != loc {
+				t.Errorf("period is in %s - %s, want %s", from.Location(), to.Location(), loc)
+			}
+		})
+	}
+}
+
+func TestParsePeriodInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		query map[string][]string
+	}{
+		{"dateFrom after dateTo", map[string][]string{"dateFrom": {"2024-07-08"}, "dateTo": {"2024-07-01"}}},
+		{"empty period", map[string][]string{"dateFrom": {"2024-07-01T09:00:00"}, "dateTo": {"2024-07-01T09:00:00"}}},
+		{"more than a year", map[string][]string{"dateFrom": {"2024-01-01"}, "dateTo": {"2025-01-01"}}},
+		{"garbage dateFrom", map[string][]string{"dateFrom": {"yesterday"}}},
+		{"garbage dateTo", map[string][]string{"dateTo": {"07/01/2024"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parsePeriod(tt.query, time.UTC, time.Now()); !errors.Is(err, ErrInvalidPeriod) {
+				t.Errorf("err = %v, want ErrInvalidPeriod", err)
+			}
+		})
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	schedule := model.Schedule{WeeklyHours: 40, WorkDays: []int{1, 2, 3, 4, 5}, DayStart: "09:00", DayEnd: "18:00"}
+	type session struct{ start, stop string }
+	tests := []struct {
+		name     string
+		tz       string
+		from, to string
+		now      string
+		sessions []session
+		days     int
+		weeks    []string
+		total    model.OvertimeEntry
+		// entries are days to check, by date.
+		entries map[string]model.OvertimeEntry
+	}{
+		{
+			name: "week", tz: "UTC", from: "2024-07-01T00:00:00Z", to: "2024-07-08T00:00:00Z",
+			sessions: []session{{"2024-07-01T08:00:00Z", "2024-07-01T18:30:00Z"}},
+			days:     7, weeks: []string{"2024-07-01"},
+			total: model.OvertimeEntry{Date: "2024-07-01", Tracked: 37800, Expected: 144000, Undertime: 106200, OutsideHours: 5400},
+			entries: map[string]model.OvertimeEntry{
+				"2024-07-01": {Date: "2024-07-01", Tracked: 37800, Expected: 28800, Overtime: 9000, OutsideHours: 5400},
+				"2024-07-06": {Date: "2024-07-06"},
+			},
+		},
+		{
+			name: "month", tz: "UTC", from: "2024-02-01T00:00:00Z", to: "2024-03-01T00:00:00Z",
+			days:  29,
+			weeks: []string{"2024-01-29", "2024-02-05", "2024-02-12", "2024-02-19", "2024-02-26"},
+			total: model.OvertimeEntry{Date: "2024-02-01", Expected: 604800, Undertime: 604800},
+		},
+		{
+			name: "session from before the period", tz: "UTC", from: "2024-07-01T00:00:00Z", to: "2024-07-02T00:00:00Z",
+			sessions: []session{{"2024-06-30T22:00:00Z", "2024-07-01T10:00:00Z"}},
+			days:     1, weeks: []string{"2024-07-01"},
+			total: model.OvertimeEntry{Date: "2024-07-01", Tracked: 36000, Expected: 28800, Overtime: 7200, OutsideHours: 32400},
+		},
+		{
+			name: "session past midnight and the period end", tz: "UTC", from: "2024-07-01T00:00:00Z", to: "2024-07-03T00:00:00Z",
+			sessions: []session{{"2024-07-02T17:00:00Z", "2024-07-03T02:00:00Z"}},
+			days:     2, weeks: []string{"2024-07-01"},
+			total: model.OvertimeEntry{Date: "2024-07-01", Tracked: 25200, Expected: 57600, Undertime: 32400, OutsideHours: 21600},
+			entries: map[string]model.OvertimeEntry{
+				"2024-07-02": {Date: "2024-07-02", Tracked: 25200, Expected: 28800, Undertime: 3600, OutsideHours: 21600},
+			},
+		},
+		{
+			name: "period within a day", tz: "UTC", from: "2024-07-01T12:00:00Z", to: "2024-07-01T15:00:00Z",
+			sessions: []session{{"2024-07-01T08:00:00Z", "2024-07-01T18:00:00Z"}},
+			days:     1, weeks: []string{"2024-07-01"},
+			total: model.OvertimeEntry{Date: "2024-07-01", Tracked: 10800, Expected: 28800, Undertime: 18000},
+		},
+		{
+			name: "running session ends now", tz: "UTC", from: "2024-07-01T00:00:00Z", to: "2024-07-02T00:00:00Z", now: "2024-07-01T12:00:00Z",
+			sessions: []session{{"2024-07-01T09:00:00Z", ""}},
+			days:     1, weeks: []string{"2024-07-01"},
+			total: model.OvertimeEntry{Date: "2024-07-01", Tracked: 10800, Expected: 28800, Undertime: 18000},
+		},
+		{
+			name: "working hours in the timezone", tz: "Asia/Tokyo", from: "2024-07-01T00:00:00+09:00", to: "2024-07-02T00:00:00+09:00",
+			sessions: []session{{"2024-07-01T00:00:00Z", "2024-07-01T09:00:00Z"}},
+			days:     1, weeks: []string{"2024-07-01"},
+			total: model.OvertimeEntry{Date: "2024-07-01", Tracked: 32400, Expected: 28800, Overtime: 3600},
+		},
+		{
+			name: "same session in UTC", tz: "UTC", from: "2024-07-01T00:00:00Z", to: "2024-07-02T00:00:00Z",
+			sessions: []session{{"2024-07-01T00:00:00Z", "2024-07-01T09:00:00Z"}},
+			days:     1, weeks: []string{"2024-07-01"},
+			total: model.OvertimeEntry{Date: "2024-07-01", Tracked: 32400, Expected: 28800, Overtime: 3600, OutsideHours: 32400},
+		},
+		{
+			name: "day summer time starts has 23 hours", tz: "Europe/Berlin", from: "2024-03-31T00:00:00+01:00", to: "2024-04-01T00:00:00+02:00",
+			sessions: []session{{"2024-03-30T23:00:00Z", "2024-03-31T22:00:00Z"}},
+			days:     1, weeks: []string{"2024-03-25"},
+			total: model.OvertimeEntry{Date: "2024-03-31", Tracked: 82800, Overtime: 82800, OutsideHours: 82800},
+		},
+		{
+			name: "day summer time ends has 25 hours", tz: "Europe/Berlin", from: "2024-10-27T00:00:00+02:00", to: "2024-10-28T00:00:00+01:00",
+			sessions: []session{{"2024-10-26T22:00:00Z", "2024-10-27T23:00:00Z"}},
+			days:     1, weeks: []string{"2024-10-21"},
+			total: model.OvertimeEntry{Date: "2024-10-27", Tracked: 90000, Overtime: 90000, OutsideHours: 90000},
+		},
+		{
+			name: "working hours follow the clock change", tz: "Europe/Berlin", from: "2024-03-25T00:00:00+01:00", to: "2024-04-02T00:00:00+02:00",
+			sessions: []session{
+				{"2024-03-25T08:00:00Z", "2024-03-25T17:00:00Z"},
+				{"2024-04-01T07:00:00Z", "2024-04-01T16:00:00Z"},
+			},
+			days: 8, weeks: []string{"2024-03-25", "2024-04-01"},
+			total: model.OvertimeEntry{Date: "2024-03-25", Tracked: 64800, Expected: 172800, Undertime: 108000},
+			entries: map[string]model.OvertimeEntry{
+				"2024-04-01": {Date: "2024-04-01", Tracked: 32400, Expected: 28800, Overtime: 3600},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLocation(t, tt.tz)
+			now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+			if tt.now != "" {
+				now = mustTime(t, tt.now)
+			}
+			sessions := []model.Session{}
+			for _, s := range tt.sessions {
+				session := model.Session{StartedAt: mustTime(t, s.start)}
+				if s.stop != "" {
+					stop := mustTime(t, s.stop)
+					session.StoppedAt = &stop
+				}
+				sessions = append(sessions, session)
+			}
+			from, to := mustTime(t, tt.from).In(loc), mustTime(t, tt.to).In(loc)
+
+			report := buildReport(schedule, sessions, nil, from, to, loc, now)
+
+			if report.Timezone != tt.tz {
+				t.Errorf("timezone = %q, want %q", report.Timezone, tt.tz)
+			}
+			if len(report.Days) != tt.days {
+				t.Errorf("days = %d, want %d", len(report.Days), tt.days)
+			}
+			weeks := []string{}
+			for _, week := range report.Weeks {
+				weeks = append(weeks, week.Date)
+			}
+			if !slices.Equal(weeks, tt.weeks) {
+				t.Errorf("weeks = %v, want %v", weeks, tt.weeks)
+			}
+			if report.Total != tt.total {
+				t.Errorf("total = %+v, want %+v", report.Total, tt.total)
+			}
+			checkEntries(t, report, tt.entries)
+		})
+	}
+}
+
+// checkEntries compares days of the report with the wanted entries of the
+// same dates.
+func checkEntries(t *testing.T, report model.OvertimeReport, want map[string]model.OvertimeEntry) {
+	t.Helper()
+	got := map[string]model.OvertimeEntry{}
+	for _, day := range report.Days {
+		got[day.Date] = day
+	}
+	for date, entry := range want {
+		if got[date] != entry {
+			t.Errorf("%s = %+v, want %+v", date, got[date], entry)
+		}
+	}
+}
+
+func TestExpandAbsences(t *testing.T) {
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 7, 7, 0, 0, 0, 0, time.UTC)
+	absences := []model.Absence{
+		{Id: 1, Kind: model.AbsenceSickLeave, DateFrom: "2024-07-03", DateTo: "2024-07-03"},
+		{Id: 2, Kind: model.AbsenceVacation, DateFrom: "2024-06-28", DateTo: "2024-07-03"},
+		{Id: 3, Kind: model.AbsenceHoliday, DateFrom: "2024-07-07", DateTo: "2024-07-10"},
+		{Id: 4, Kind: model.AbsenceHoliday, DateFrom: "2024-07-20", DateTo: "2024-07-21"},
+	}
+	want := []model.AbsenceDay{
+		{Date: "2024-07-01", Kind: model.AbsenceVacation, AbsenceId: 2},
+		{Date: "2024-07-02", Kind: model.AbsenceVacation, AbsenceId: 2},
+		{Date: "2024-07-03", Kind: model.AbsenceSickLeave, AbsenceId: 1},
+		{Date: "2024-07-07", Kind: model.AbsenceHoliday, AbsenceId: 3},
+	}
+	if got := expandAbsences(absences, from, to); !slices.Equal(got, want) {
+		t.Errorf("days = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildReportAbsences(t *testing.T) {
+	schedule := model.Schedule{WeeklyHours: 40, WorkDays: []int{1, 2, 3, 4, 5}, DayStart: "09:00", DayEnd: "18:00"}
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	// A vacation from the week before and a holiday into the next week are
+	// clipped to the period.
+	absences := expandAbsences([]model.Absence{
+		{Id: 1, Kind: model.AbsenceVacation, DateFrom: "2024-06-28", DateTo: "2024-07-02"},
+		{Id: 2, Kind: model.AbsenceHoliday, DateFrom: "2024-07-05", DateTo: "2024-07-10"},
+	}, from, to.AddDate(0, 0, -1))
+	start := time.Date(2024, 7, 2, 9, 0, 0, 0, time.UTC)
+	stop := start.Add(2 * time.Hour)
+	sessions := []model.Session{{StartedAt: start, StoppedAt: &stop}}
+
+	report := buildReport(schedule, sessions, absences, from, to, time.UTC, stop)
+
+	if len(report.Days) != 7 {
+		t.Fatalf("days = %d, want 7", len(report.Days))
+	}
+	// Two of five work days are left; time tracked on a vacation day is
+	// all outside working hours.
+	want := model.OvertimeEntry{Date: "2024-07-01", Tracked: 7200, Expected: 57600, Undertime: 50400, OutsideHours: 7200}
+	if report.Total != want {
+		t.Errorf("total = %+v, want %+v", report.Total, want)
+	}
+	checkEntries(t, report, map[string]model.OvertimeEntry{
+		"2024-07-01": {Date: "2024-07-01", Absence: model.AbsenceVacation},
+		"2024-07-02": {Date: "2024-07-02", Tracked: 7200, Overtime: 7200, OutsideHours: 7200, Absence: model.AbsenceVacation},
+		"2024-07-03": {Date: "2024-07-03", Expected: 28800, Undertime: 28800},
+		"2024-07-05": {Date: "2024-07-05", Absence: model.AbsenceHoliday},
+		"2024-07-07": {Date: "2024-07-07", Absence: model.AbsenceHoliday},
+	})
+}
@@ -0,0 +1,131 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/period"
+)
+
+var (
+	ErrScheduleNotFound = errors.New("schedule not set")
+	ErrInvalidSchedule  = errors.New("invalid schedule")
+	ErrInvalidPeriod    = errors.New("invalid period")
+)
+
+type scheduleService struct {
+	storage storage
+}
+
+type storage interface {
+	ScheduleExists(userId int) bool
+	GetSchedule(userId int) (model.Schedule, error)
+	SaveSchedule(schedule model.Schedule) error
+	GetSessionsByUser(userId int, from, to time.Time) ([]model.Session, error)
+	UserExists(userId int) bool
+	GetUser(userId int) (model.User, error)
+	SaveAbsence(absence *model.Absence) error
+	UpdateAbsence(absence model.Absence) error
+	DeleteAbsence(absenceId int) error
+	AbsenceExists(absenceId int) bool
+	GetAbsence(absenceId int) (model.Absence, error)
+	GetAbsences(query map[string][]string) ([]model.Absence, error)
+	GetAbsencesForUser(userId int, region string, from, to time.Time) ([]model.Absence, error)
+}
+
+func New(storage storage) *scheduleService {
+	return &scheduleService{
+		storage: storage,
+	}
+}
+
+// WithStorage returns a copy of the service working on storage, usually the
+// storage of one organization.
+func (s *scheduleService) WithStorage(storage storage) *scheduleService {
+	scoped := *s
+	scoped.storage = storage
+	return &scoped
+}
+
+func (s *scheduleService) GetSchedule(userId int) (model.Schedule, error) {
+	if !s.storage.ScheduleExists(userId) {
+		return model.Schedule{}, ErrScheduleNotFound
+	}
+	return s.storage.GetSchedule(userId)
+}
+
+// SaveSchedule stores the schedule. Without an explicit timezone the
+// schedule follows the user's timezone.
+func (s *scheduleService) SaveSchedule(schedule model.Schedule) error {
+	if schedule.Timezone == "" {
+		user, err := s.storage.GetUser(schedule.UserId)
+		if err != nil {
+			return err
+		}
+		schedule.Timezone = user.Timezone
+	}
+	if err := validate(schedule); err != nil {
+		return err
+	}
+	return s.storage.SaveSchedule(schedule)
+}
+
+func (s *scheduleService) GetOvertimeReport(userId int, query map[string][]string) (model.OvertimeReport, error) {
+	schedule, err := s.GetSchedule(userId)
+	if err != nil {
+		return model.OvertimeReport{}, err
+	}
+	loc, err := period.Location(query, schedule.Timezone)
+	if err != nil {
+		return model.OvertimeReport{}, err
+	}
+	from, to, err := parsePeriod(query, loc, time.Now())
+	if err != nil {
+		return model.OvertimeReport{}, err
+	}
+	sessions, err := s.storage.GetSessionsByUser(userId, from, to)
+	if err != nil {
+		return model.OvertimeReport{}, err
+	}
+	lastDay := period.StartOfDay(to.Add(-time.Nanosecond))
+	absences, err := s.storage.GetAbsencesForUser(userId, schedule.Region, period.StartOfDay(from), lastDay)
+	if err != nil {
+		return model.OvertimeReport{}, err
+	}
+	report := buildReport(schedule, sessions, expandAbsences(absences, period.StartOfDay(from), lastDay), from, to, loc, time.Now())
+	report.UserId = userId
+	return report, nil
+}
+
+func validate(schedule model.Schedule) error {
+	if schedule.WeeklyHours < 0 || schedule.WeeklyHours > 168 {
+		return fmt.Errorf("%w: weekly_hours must be between 0 and 168", ErrInvalidSchedule)
+	}
+	if len(schedule.WorkDays) == 0 && schedule.WeeklyHours > 0 {
+		return fmt.Errorf("%w: work_days must not be empty", ErrInvalidSchedule)
+	}
+	seen := map[int]bool{}
+	for _, day := range schedule.WorkDays {
+		if day < 0 || day > 6 || seen[day] {
+			return fmt.Errorf("%w: work_days must be unique weekdays from 0 (Sunday) to 6 (Saturday)", ErrInvalidSchedule)
+		}
+		seen[day] = true
+	}
+	start, err := time.Parse("15:04", schedule.DayStart)
+	if err != nil {
+		return fmt.Errorf("%w: day_start must be in HH:MM format", ErrInvalidSchedule)
+	}
+	end, err := time.Parse("15:04", schedule.DayEnd)
+	if err != nil {
+		return fmt.Errorf("%w: day_end must be in HH:MM format", ErrInvalidSchedule)
+	}
+	if !start.Before(end) {
+		return fmt.Errorf("%w: day_start must be before day_end", ErrInvalidSchedule)
+	}
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, schedule.Timezone)
+	}
+	return nil
+}
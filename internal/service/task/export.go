@@ -0,0 +1,108 @@
+package task
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/duration"
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/passport"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportUser collects everything stored about the user, deleted or not:
+// profile, schedule, own absences, all tasks with their sessions, the cached
+// people API response and the audit records up to now. Durations are rounded like in
+// workhours.
+func (t *taskService) ExportUser(userId int, query map[string][]string) (model.UserExport, error) {
+	export := model.UserExport{ExportedAt: time.Now().UTC()}
+	rounding, err := duration.RoundingFromQuery(query, defaultRounding)
+	if err != nil {
+		return export, err
+	}
+	user, err := t.storage.GetUser(userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return export, ErrUserNotFound
+	}
+	if err != nil {
+		return export, err
+	}
+	export.User = user
+	if t.storage.ScheduleExists(userId) {
+		schedule, err := t.storage.GetSchedule(userId)
+		if err != nil {
+			return export, err
+		}
+		export.Schedule = &schedule
+	}
+	export.Absences, err = t.storage.GetAbsences(map[string][]string{"userId": {strconv.Itoa(userId)}})
+	if err != nil {
+		return export, err
+	}
+	export.Tasks, err = t.storage.GetTasksByUser(userId)
+	if err != nil {
+		return export, err
+	}
+	export.Sessions = []model.Session{}
+	if len(export.Tasks) > 0 {
+		taskIds := make([]int, 0, len(export.Tasks))
+		for _, task := range export.Tasks {
+			taskIds = append(taskIds, task.Id)
+		}
+		export.Sessions, err = t.storage.GetSessionsByTasks(taskIds)
+		if err != nil {
+			return export, err
+		}
+	}
+	if err := t.applyRounding(export.Tasks, rounding); err != nil {
+		return export, err
+	}
+	if parsed, err := passport.Parse(user.PassportNumber); err == nil {
+		entry, found, err := t.storage.GetPeopleCacheEntry(parsed.String())
+		if err != nil {
+			return export, err
+		}
+		if found && !entry.NotFound {
+			export.PeopleAPI = entry.Payload
+		}
+	}
+	export.Audit, err = t.storage.GetAuditRecords(userId)
+	if err != nil {
+		return export, err
+	}
+	return export, nil
+}
+
+// RecordAudit stores an action taken on a user.
+func (t *taskService) RecordAudit(record model.AuditRecord) (model.AuditRecord, error) {
+	err := t.storage.SaveAuditRecord(&record)
+	return record, err
+}
+
+// AnonymizeUser erases the user's personal data while keeping its tasks and
+// sessions, so accounting totals stay intact. The cached people API response
+// for the passport is dropped as well.
+func (t *taskService) AnonymizeUser(userId int) (model.User, error) {
+	user, err := t.storage.GetUser(userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrUserNotFound
+	}
+	if err != nil {
+		return user, err
+	}
+	if user.AnonymizedAt != nil {
+		return user, nil
+	}
+	if err := t.storage.AnonymizeUser(userId); err != nil {
+		return user, err
+	}
+	if parsed, err := passport.Parse(user.PassportNumber); err == nil {
+		if err := t.people.Forget(parsed.Series, parsed.Number); err != nil {
+			logrus.Info(fmt.Errorf("forget cached lookup of user %d: %w", userId, err))
+		}
+	}
+	return t.storage.GetUser(userId)
+}
@@ -0,0 +1,277 @@
+package task
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/client/people"
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/passport"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ImportCSV        = "csv"
+	ImportJSONLines  = "jsonl"
+	importColumnList = "passportNumber,name,surname,patronymic,address"
+)
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// ImportConfig bounds bulk imports: how many rows are enriched at once and
+// how many rows a single import may have.
+type ImportConfig struct {
+	Concurrency int
+	MaxRows     int
+}
+
+var importConfig = ImportConfig{Concurrency: 4, MaxRows: 1000}
+
+// ImportConfigFromEnv reads IMPORT_CONCURRENCY and IMPORT_MAX_ROWS.
+func ImportConfigFromEnv() (ImportConfig, error) {
+	config := importConfig
+	for key, target := range map[string]*int{
+		"IMPORT_CONCURRENCY": &config.Concurrency,
+		"IMPORT_MAX_ROWS":    &config.MaxRows,
+	} {
+		if val := os.Getenv(key); val != "" {
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				return config, fmt.Errorf("%s must be a positive number, got %q", key, val)
+			}
+			*target = n
+		}
+	}
+	return config, nil
+}
+
+// ImportUsers registers the users listed in body, CSV or JSON lines, with at
+// most IMPORT_CONCURRENCY rows in flight. A dry run validates the rows and
+// checks them for duplicates without calling the people API or storing
+// anything. Row problems end up in the report; only an unreadable body fails
+// the import as a whole.
+func (t *taskService) ImportUsers(ctx context.Context, format string, body io.Reader, dryRun bool) (model.ImportReport, error) {
+	report := model.ImportReport{DryRun: dryRun, Counts: map[string]int{}}
+	var rows []model.ImportRow
+	var err error
+	switch format {
+	case ImportCSV:
+		rows, err = parseCSV(body)
+	case ImportJSONLines:
+		rows, err = parseJSONLines(body)
+	default:
+		err = fmt.Errorf("%w: unsupported format %q", ErrInvalidImport, format)
+	}
+	if err != nil {
+		return report, err
+	}
+	if len(rows) > importConfig.MaxRows {
+		return report, fmt.Errorf("%w: at most %d rows per import", ErrInvalidImport, importConfig.MaxRows)
+	}
+
+	report.Total = len(rows)
+	report.Rows = make([]model.ImportResult, len(rows))
+	seen := map[string]int{}
+	pending := []int{}
+	for i, row := range rows {
+		result, parsed, ok := checkImportRow(row)
+		if ok {
+			if line, dup := seen[parsed.String()]; dup {
+				result.Status = model.ImportDuplicate
+				result.Error = fmt.Sprintf("same passport as line %d", line)
+				ok = false
+			}
+			seen[parsed.String()] = row.Line
+		}
+		report.Rows[i] = result
+		if ok {
+			pending = append(pending, i)
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < importConfig.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				report.Rows[i] = t.importRow(ctx, rows[i], report.Rows[i], dryRun)
+			}
+		}()
+	}
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, result := range report.Rows {
+		report.Counts[result.Status]++
+	}
+	return report, nil
+}
+
+// checkImportRow validates a row and reports whether it can be imported.
+func checkImportRow(row model.ImportRow) (model.ImportResult, passport.Passport, bool) {
+	result := model.ImportResult{Line: row.Line, PassportNumber: passport.Mask(row.PassportNumber)}
+	if row.Problem != "" {
+		result.Status = model.ImportInvalid
+		result.Error = row.Problem
+		return result, passport.Passport{}, false
+	}
+	parsed, err := passport.Parse(row.PassportNumber)
+	if err != nil {
+		result.Status = model.ImportInvalid
+		result.Error = err.Error()
+		return result, parsed, false
+	}
+	result.PassportNumber = passport.Mask(parsed.String())
+	if (row.Name == "") != (row.Surname == "") {
+		result.Status = model.ImportInvalid
+		result.Error = "name and surname must be given together"
+		return result, parsed, false
+	}
+	return result, parsed, true
+}
+
+func (t *taskService) importRow(ctx context.Context, row model.ImportRow, result model.ImportResult, dryRun bool) model.ImportResult {
+	parsed, _ := passport.Parse(row.PassportNumber)
+	if dryRun {
+		existing, found, err := t.storage.GetUserByPassport(parsed.String())
+		switch {
+		case err != nil:
+			logrus.Info(err)
+			result.Status = model.ImportError
+			result.Error = "Internal Server Error"
+		case found:
+			result.Status = model.ImportDuplicate
+			result.UserId = existing.Id
+		default:
+			result.Status = model.ImportCreated
+		}
+		return result
+	}
+
+	var known *people.Person
+	if row.Name != "" {
+		known = &people.Person{Name: row.Name, Surname: row.Surname, Patronymic: row.Patronymic, Address: row.Address}
+	}
+	user, err := t.register(ctx, parsed, known, false)
+	switch {
+	case err == nil:
+		result.Status = model.ImportCreated
+		result.UserId = user.Id
+	case errors.Is(err, ErrDuplicateUser):
+		result.Status = model.ImportDuplicate
+		result.UserId = user.Id
+	case errors.Is(err, ErrDeletedUser):
+		result.Status = model.ImportDuplicate
+		result.Error = err.Error()
+	case errors.Is(err, people.ErrNotFound), errors.Is(err, people.ErrBadResponse), errors.Is(err, people.ErrUnavailable),
+		errors.Is(err, people.ErrTimeout), errors.Is(err, people.ErrCircuitOpen):
+		result.Status = model.ImportEnrichmentFailed
+		result.Error = err.Error()
+	default:
+		logrus.Info(err)
+		result.Status = model.ImportError
+		result.Error = "Internal Server Error"
+	}
+	return result
+}
+
+// parseCSV reads rows of passportNumber,name,surname,patronymic,address. A
+// header row naming the columns may reorder them or leave some out.
+func parseCSV(body io.Reader) ([]model.ImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	columns := strings.Split(importColumnList, ",")
+	rows := []model.ImportRow{}
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+		}
+		line++
+		if line == 1 && isImportHeader(record) {
+			columns = make([]string, len(record))
+			for i, name := range record {
+				columns[i] = strings.TrimSpace(name)
+			}
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		// Blank lines are skipped by the reader, so ask it for the line.
+		fileLine, _ := reader.FieldPos(0)
+		row := model.ImportRow{Line: fileLine}
+		for i, value := range record {
+			if i >= len(columns) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(columns[i]) {
+			case "passportnumber":
+				row.PassportNumber = value
+			case "name":
+				row.Name = value
+			case "surname":
+				row.Surname = value
+			case "patronymic":
+				row.Patronymic = value
+			case "address":
+				row.Address = value
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func isImportHeader(record []string) bool {
+	for _, name := range record {
+		if strings.EqualFold(strings.TrimSpace(name), "passportNumber") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseJSONLines reads one JSON object per line, blank lines skipped. Lines
+// that aren't valid JSON become invalid rows.
+func parseJSONLines(body io.Reader) ([]model.ImportRow, error) {
+	scanner := bufio.NewScanner(body)
+	rows := []model.ImportRow{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		row := model.ImportRow{}
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			row = model.ImportRow{Problem: "malformed JSON: " + err.Error()}
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,85 @@
+package task
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/passport"
+	"github.com/TimeTracker-Effective-Mobile/internal/validation"
+)
+
+// PatchUser applies a JSON Merge Patch (RFC 7396) to the user: only the
+// members present in the patch change, and null clears optional fields. A
+// pending user is activated, like with UpdateUser.
+func (t *taskService) PatchUser(userId int, patch map[string]json.RawMessage) (model.User, error) {
+	user, err := t.storage.GetUser(userId)
+	if err != nil {
+		return user, err
+	}
+	fields := map[string]*string{
+		"passportNumber": &user.PassportNumber,
+		"name":           &user.Name,
+		"surname":        &user.Surname,
+		"patronymic":     &user.Patronymic,
+		"address":        &user.Address,
+		"timezone":       &user.Timezone,
+	}
+	optional := map[string]bool{"patronymic": true}
+	problems := map[string]string{}
+	for key, raw := range patch {
+		target, ok := fields[key]
+		if !ok {
+			problems[key] = "cannot be changed"
+			continue
+		}
+		if string(raw) == "null" {
+			if !optional[key] {
+				problems[key] = "is required"
+				continue
+			}
+			*target = ""
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			problems[key] = "must be a string"
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" && !optional[key] {
+			problems[key] = "must not be empty"
+			continue
+		}
+		*target = value
+	}
+	if _, ok := patch["passportNumber"]; ok && problems["passportNumber"] == "" {
+		parsed, err := passport.Parse(user.PassportNumber)
+		if err != nil {
+			problems["passportNumber"] = "must be a 4-digit series and a 6-digit number"
+		} else {
+			user.PassportNumber = parsed.String()
+		}
+	}
+	if _, ok := patch["timezone"]; ok && problems["timezone"] == "" {
+		if _, err := time.LoadLocation(user.Timezone); err != nil {
+			problems["timezone"] = "unknown timezone"
+		}
+	}
+	if len(problems) > 0 {
+		return user, &validation.Error{Message: "invalid user", Fields: problems}
+	}
+	if len(patch) == 0 {
+		return user, nil
+	}
+	existing, found, err := t.storage.GetUserByPassport(user.PassportNumber)
+	if err != nil {
+		return user, err
+	}
+	if found && existing.Id != user.Id {
+		return user, ErrDuplicateUser
+	}
+	user.Status = editedStatus(user.Status)
+	return user, t.storage.UpdateUser(user)
+}
@@ -0,0 +1,47 @@
+package task
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/pagination"
+	"github.com/TimeTracker-Effective-Mobile/internal/validation"
+)
+
+const maxSearchLength = 200
+
+// Search looks up users and tasks matching the q parameter. The type
+// parameter narrows the hits to users or tasks and limit caps their number.
+func (t *taskService) Search(query map[string][]string) ([]model.SearchHit, error) {
+	problems := map[string]string{}
+	text := ""
+	if val, ok := query["q"]; ok {
+		text = strings.TrimSpace(val[0])
+	}
+	switch {
+	case text == "":
+		problems["q"] = "must not be empty"
+	case utf8.RuneCountInString(text) > maxSearchLength:
+		problems["q"] = "must be at most 200 characters"
+	}
+	types := []string{}
+	for _, val := range query["type"] {
+		for _, kind := range strings.Split(val, ",") {
+			switch kind {
+			case model.SearchUser, model.SearchTask:
+				types = append(types, kind)
+			default:
+				problems["type"] = "must be user or task"
+			}
+		}
+	}
+	if len(types) == 0 {
+		types = []string{model.SearchUser, model.SearchTask}
+	}
+	if len(problems) > 0 {
+		return nil, &validation.Error{Message: "invalid search", Fields: problems}
+	}
+	page := pagination.FromQuery(query, pageLimits.Default, pageLimits)
+	return t.storage.Search(text, types, page.Limit)
+}
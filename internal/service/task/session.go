@@ -0,0 +1,63 @@
+package task
+
+import (
+	"errors"
+	"time"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/validation"
+)
+
+var ErrSessionNotFound = errors.New("session not exist")
+
+func (t *taskService) GetSession(sessionId int) (model.Session, error) {
+	session, found, err := t.storage.GetSession(sessionId)
+	if err != nil {
+		return session, err
+	}
+	if !found {
+		return session, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// UpdateSession corrects when a session started and stopped, updating the
+// duration of its task. A running session stays running: it can only get
+// another start, and is stopped by stopping its task.
+func (t *taskService) UpdateSession(sessionId int, startedAt time.Time, stoppedAt *time.Time) (model.Session, error) {
+	session, err := t.GetSession(sessionId)
+	if err != nil {
+		return session, err
+	}
+	problems := map[string]string{}
+	now := time.Now()
+	if startedAt.IsZero() || startedAt.After(now) {
+		problems["started_at"] = "required and must not be in the future"
+	}
+	switch {
+	case session.StoppedAt == nil && stoppedAt != nil:
+		problems["stopped_at"] = "the session is running, stop its task instead"
+	case session.StoppedAt != nil && stoppedAt == nil:
+		problems["stopped_at"] = "required for a stopped session"
+	case stoppedAt != nil && (stoppedAt.After(now) || !stoppedAt.After(startedAt)):
+		problems["stopped_at"] = "must be after started_at and not in the future"
+	}
+	if len(problems) > 0 {
+		return session, &validation.Error{Message: "invalid session", Fields: problems}
+	}
+	// Sessions are stored in UTC without a time zone.
+	session.StartedAt = startedAt.UTC()
+	session.StoppedAt = nil
+	if stoppedAt != nil {
+		stopped := stoppedAt.UTC()
+		session.StoppedAt = &stopped
+	}
+	overlaps, err := t.storage.SessionOverlaps(session)
+	if err != nil {
+		return session, err
+	}
+	if overlaps {
+		return session, &validation.Error{Message: "invalid session", Fields: map[string]string{"started_at": "overlaps another session of the task"}}
+	}
+	return session, t.storage.UpdateSession(session)
+}
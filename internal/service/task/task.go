@@ -1,24 +1,40 @@
 package task
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
 	"strings"
+	"time"
 
+	"github.com/TimeTracker-Effective-Mobile/internal/client/people"
+	"github.com/TimeTracker-Effective-Mobile/internal/duration"
 	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/pagination"
+	"github.com/TimeTracker-Effective-Mobile/internal/passport"
+	"github.com/TimeTracker-Effective-Mobile/internal/period"
 	"github.com/sirupsen/logrus"
 )
 
 type taskService struct {
-	storage storage
+	storage  storage
+	people   peopleClient
+	enricher enricher
+}
+
+type enricher interface {
+	Enqueue(userId int) error
+}
+
+type peopleClient interface {
+	GetInfo(ctx context.Context, series, number string) (people.Person, error)
+	Refresh(ctx context.Context, series, number string) (people.Person, error)
+	Forget(series, number string) error
 }
 
 type storage interface {
-	GetUsersInfo(query map[string][]string) ([]model.User, error)
-	GetSortedTaskByUser(userId int, query map[string][]string) ([]model.Task, error)
+	GetUsersInfo(query map[string][]string, page pagination.Page) ([]model.User, pagination.Result, error)
+	GetSortedTaskByUser(userId int, query map[string][]string, page pagination.Page) ([]model.Task, pagination.Result, error)
 	StartNewTask(userId int, name string) (model.Task, error)
 	StartExistingTask(taskId int) error
 	TaskExists(taskId int) bool
@@ -26,49 +42,302 @@ type storage interface {
 	IsActiveTask(taskId int) bool
 	StopTask(taskId int) (model.Task, error)
 	DeleteUser(userId int) error
+	RestoreUser(userId int) (bool, error)
+	PurgeUser(userId int) (bool, error)
+	AnonymizeUser(userId int) error
+	GetTasksByUser(userId int) ([]model.Task, error)
+	ScheduleExists(userId int) bool
+	GetSchedule(userId int) (model.Schedule, error)
+	GetAbsences(query map[string][]string) ([]model.Absence, error)
+	GetPeopleCacheEntry(passportNumber string) (model.PeopleCacheEntry, bool, error)
 	UpdateUser(user model.User) error
 	SaveUser(user *model.User) error
+	FailPendingUser(userId int) (bool, error)
+	GetUser(userId int) (model.User, error)
+	GetTask(taskId int) (model.Task, error)
+	GetUserByPassport(passportNumber string) (model.User, bool, error)
+	GetSessionsByTasks(taskIds []int) ([]model.Session, error)
+	Search(text string, types []string, limit int) ([]model.SearchHit, error)
+	SaveTeam(team *model.Team) (bool, error)
+	GetTeams() ([]model.Team, error)
+	TeamExists(teamId int) (bool, error)
+	SetUserTeam(userId int, teamId *int) (bool, error)
+	SaveAuditRecord(record *model.AuditRecord) error
+	GetAuditRecords(userId int) ([]model.AuditRecord, error)
+	GetSession(sessionId int) (model.Session, bool, error)
+	SessionOverlaps(session model.Session) (bool, error)
+	UpdateSession(session model.Session) error
 }
 
 var (
-	externalApi string
+	ErrInvalidPeriod  = errors.New("invalid period")
+	ErrDuplicateUser  = errors.New("passport already registered")
+	ErrDeletedUser    = errors.New("passport belongs to a deleted user")
+	ErrUserNotFound   = errors.New("user not exist")
+	ErrAnonymizedUser = errors.New("user is anonymized")
 )
 
-func New(storage storage) *taskService {
-	externalApi = os.Getenv("EXTERNAL_USER_API")
+var (
+	defaultRounding duration.Rounding
+	pageLimits      pagination.Limits
+)
+
+func New(storage storage, people peopleClient, enricher enricher) *taskService {
+	rounding, err := duration.RoundingFromEnv()
+	if err != nil {
+		logrus.Fatalf(err.Error())
+	}
+	defaultRounding = rounding
+	pageLimits, err = pagination.LimitsFromEnv()
+	if err != nil {
+		logrus.Fatalf(err.Error())
+	}
+	importConfig, err = ImportConfigFromEnv()
+	if err != nil {
+		logrus.Fatalf(err.Error())
+	}
 	return &taskService{
-		storage: storage,
+		storage:  storage,
+		people:   people,
+		enricher: enricher,
+	}
+}
+
+// WithStorage returns a copy of the service working on storage, usually the
+// storage of one organization.
+func (t *taskService) WithStorage(storage storage) *taskService {
+	scoped := *t
+	scoped.storage = storage
+	return &scoped
+}
+
+// AddUser registers the passport and enriches it from the people API. When
+// the passport is already registered the existing user is returned together
+// with ErrDuplicateUser and the API is not called; a deleted user has to be
+// restored instead (ErrDeletedUser). In async mode the user is stored as
+// pending right away and enriched in the background.
+func (t *taskService) AddUser(ctx context.Context, passportNumber string, async bool) (model.User, error) {
+	parsed, err := passport.Parse(passportNumber)
+	if err != nil {
+		return model.User{}, err
 	}
+	return t.register(ctx, parsed, nil, async)
 }
 
-func (t *taskService) AddUser(passport string) (model.User, error) {
-	fields := strings.Fields(passport)
-	query := fmt.Sprintf("%s/info?passportSerie=%s&passportNumber=%s", externalApi, fields[0], fields[1])
+// register creates the user for an already validated passport. A known
+// person skips the people API.
+func (t *taskService) register(ctx context.Context, parsed passport.Passport, known *people.Person, async bool) (model.User, error) {
 	var user model.User
-	resp, err := http.Get(query)
+	existing, found, err := t.storage.GetUserByPassport(parsed.String())
 	if err != nil {
-		logrus.Debug(err)
 		return user, err
 	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil || body == nil {
-		logrus.Debug(err)
-		return user, err
+	if found && existing.DeletedAt != nil {
+		return user, fmt.Errorf("%w: restore user %d", ErrDeletedUser, existing.Id)
+	}
+	if found {
+		return existing, ErrDuplicateUser
 	}
-	if err := json.Unmarshal(body, &user); err != nil {
-		logrus.Debugf("Can not unmarshal JSON, %s", err.Error())
+	user = model.User{
+		PassportNumber: parsed.String(),
+		Timezone:       "UTC",
+		Status:         model.UserPending,
+	}
+	if known != nil || !async {
+		person := known
+		if person == nil {
+			fetched, err := t.people.GetInfo(ctx, parsed.Series, parsed.Number)
+			if err != nil {
+				logrus.Debug(err)
+				return user, err
+			}
+			person = &fetched
+		}
+		user.Name = person.Name
+		user.Surname = person.Surname
+		user.Patronymic = person.Patronymic
+		user.Address = person.Address
+		user.Status = model.UserActive
+	}
+	err = t.storage.SaveUser(&user)
+	if err != nil {
+		// A concurrent request may have registered the passport meanwhile.
+		if existing, found, _ := t.storage.GetUserByPassport(user.PassportNumber); found {
+			return existing, ErrDuplicateUser
+		}
 		return user, err
 	}
-	return user, t.storage.SaveUser(&user)
+	if async {
+		if err := t.enricher.Enqueue(user.Id); err != nil {
+			// Nothing would pick the user up before the next restart. A
+			// failed user tells the client so, and an admin refresh can still
+			// enrich it.
+			logrus.Info(err)
+			if _, err := t.storage.FailPendingUser(user.Id); err != nil {
+				return user, err
+			}
+			user.Status = model.UserFailed
+		}
+	}
+	return user, nil
+}
+
+func (t *taskService) GetUser(userId int) (model.User, error) {
+	return t.storage.GetUser(userId)
+}
+
+// RefreshUser re-fetches the profile bypassing the lookup cache and updates
+// only the fields that changed.
+func (t *taskService) RefreshUser(ctx context.Context, userId int) (model.UserRefresh, error) {
+	result := model.UserRefresh{Changes: map[string]model.FieldChange{}}
+	user, err := t.storage.GetUser(userId)
+	if err != nil {
+		return result, err
+	}
+	if user.AnonymizedAt != nil {
+		return result, ErrAnonymizedUser
+	}
+	parsed, err := passport.Parse(user.PassportNumber)
+	if err != nil {
+		return result, err
+	}
+	person, err := t.people.Refresh(ctx, parsed.Series, parsed.Number)
+	if err != nil {
+		return result, err
+	}
+	fields := []struct {
+		name    string
+		current *string
+		fetched string
+	}{
+		{"name", &user.Name, person.Name},
+		{"surname", &user.Surname, person.Surname},
+		{"patronymic", &user.Patronymic, person.Patronymic},
+		{"address", &user.Address, person.Address},
+	}
+	for _, field := range fields {
+		if *field.current != field.fetched {
+			result.Changes[field.name] = model.FieldChange{Old: *field.current, New: field.fetched}
+			*field.current = field.fetched
+		}
+	}
+	if user.Status != model.UserActive {
+		result.Changes["status"] = model.FieldChange{Old: user.Status, New: model.UserActive}
+		user.Status = model.UserActive
+	}
+	result.User = user
+	if len(result.Changes) == 0 {
+		return result, nil
+	}
+	return result, t.storage.UpdateUser(user)
+}
+
+// GetUsersInfo returns a page of users, PAGINATION_DEFAULT_LIMIT of them
+// unless the limit parameter asks for more.
+func (t *taskService) GetUsersInfo(query map[string][]string) ([]model.User, pagination.Result, error) {
+	page := pagination.FromQuery(query, pageLimits.Default, pageLimits)
+	// Passports are matched through a hash of the canonical form, so every
+	// variant the user may type has to be normalized first.
+	normalized := make(map[string][]string, len(query))
+	for key, val := range query {
+		normalized[key] = val
+	}
+	for _, key := range []string{"passportNumber", "passportNumber[in]"} {
+		values, ok := query[key]
+		if !ok {
+			continue
+		}
+		if key == "passportNumber[in]" {
+			values = strings.Split(values[0], ",")
+		}
+		passports := make([]string, 0, len(values))
+		for _, val := range values {
+			parsed, err := passport.Parse(val)
+			if err != nil {
+				return nil, pagination.Result{}, err
+			}
+			passports = append(passports, parsed.String())
+		}
+		if key == "passportNumber[in]" {
+			passports = []string{strings.Join(passports, ",")}
+		}
+		normalized[key] = passports
+	}
+	return t.storage.GetUsersInfo(normalized, page)
 }
 
-func (t *taskService) GetUsersInfo(query map[string][]string) ([]model.User, error) {
-	return t.storage.GetUsersInfo(query)
+// GetSortedTaskByUser interprets dateFrom/dateTo in the user's timezone unless
+// the tz parameter overrides it, and presents timestamps in that timezone.
+// Durations are additionally rounded when a rounding policy is configured or
+// requested. Every task is returned unless a page is requested with limit,
+// page, offset or cursor; a page is then at most PAGINATION_MAX_LIMIT tasks.
+func (t *taskService) GetSortedTaskByUser(userId int, query map[string][]string) ([]model.Task, pagination.Result, error) {
+	var result pagination.Result
+	rounding, err := duration.RoundingFromQuery(query, defaultRounding)
+	if err != nil {
+		return nil, result, err
+	}
+	user, err := t.storage.GetUser(userId)
+	if err != nil {
+		return nil, result, err
+	}
+	loc, err := period.Location(query, user.Timezone)
+	if err != nil {
+		return nil, result, err
+	}
+	normalized := make(map[string][]string, len(query))
+	for key, val := range query {
+		normalized[key] = val
+	}
+	for _, key := range []string{"dateFrom", "dateTo"} {
+		val, ok := query[key]
+		if !ok {
+			continue
+		}
+		date, err := period.Parse(val[0], loc, key == "dateTo")
+		if err != nil {
+			return nil, result, fmt.Errorf("%w: %s must be RFC3339 or YYYY-MM-DD", ErrInvalidPeriod, key)
+		}
+		normalized[key] = []string{date.Format(time.RFC3339)}
+	}
+	// Work hours are a report: they are only cut into pages when asked to.
+	page := pagination.FromQuery(query, pageLimits.Max, pageLimits)
+	if !pagination.Requested(query) {
+		page.Limit = 0
+	}
+	tasks, result, err := t.storage.GetSortedTaskByUser(userId, normalized, page)
+	if err != nil {
+		return tasks, result, err
+	}
+	for i := range tasks {
+		tasks[i].CreatedAt = tasks[i].CreatedAt.In(loc)
+		tasks[i].UpdatedAt = tasks[i].UpdatedAt.In(loc)
+	}
+	return tasks, result, t.applyRounding(tasks, rounding)
+}
+
+func (t *taskService) applyRounding(tasks []model.Task, rounding duration.Rounding) error {
+	if !rounding.Enabled() {
+		return nil
+	}
+	sessions := []model.Session{}
+	if rounding.Scope == duration.PerSession && len(tasks) > 0 {
+		taskIds := make([]int, 0, len(tasks))
+		for _, task := range tasks {
+			taskIds = append(taskIds, task.Id)
+		}
+		var err error
+		sessions, err = t.storage.GetSessionsByTasks(taskIds)
+		if err != nil {
+			return err
+		}
+	}
+	rounding.Apply(tasks, sessions, time.Now())
+	return nil
 }
 
-func (t *taskService) GetSortedTaskByUser(userId int, query map[string][]string) ([]model.Task, error) {
-	return t.storage.GetSortedTaskByUser(userId, query)
+func (t *taskService) GetTask(taskId int) (model.Task, error) {
+	return t.storage.GetTask(taskId)
 }
 
 func (t *taskService) StartNewTask(userId int, name string) (model.Task, error) {
@@ -95,10 +364,70 @@ func (t *taskService) StopTask(taskId int) (model.Task, error) {
 	return t.storage.StopTask(taskId)
 }
 
+// DeleteUser soft-deletes the user, keeping its tasks for payroll history.
 func (t *taskService) DeleteUser(userId int) error {
 	return t.storage.DeleteUser(userId)
 }
 
+// RestoreUser brings back a soft-deleted user. It fails with ErrUserNotFound
+// when there is no deleted user with this id.
+func (t *taskService) RestoreUser(userId int) (model.User, error) {
+	restored, err := t.storage.RestoreUser(userId)
+	if err != nil {
+		return model.User{}, err
+	}
+	if !restored {
+		return model.User{}, ErrUserNotFound
+	}
+	return t.storage.GetUser(userId)
+}
+
+// PurgeUser permanently deletes the user with its tasks, sessions, schedule
+// and absences.
+func (t *taskService) PurgeUser(userId int) error {
+	purged, err := t.storage.PurgeUser(userId)
+	if err != nil {
+		return err
+	}
+	if !purged {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// editedStatus is the status of a user after an edit. Editing a pending user
+// activates it: the edit takes the place of the lookup in flight, which
+// EnrichUser then discards instead of overwriting the edited fields.
+func editedStatus(status string) string {
+	if status == model.UserPending {
+		return model.UserActive
+	}
+	return status
+}
+
 func (t *taskService) UpdateUser(user model.User) error {
+	parsed, err := passport.Parse(user.PassportNumber)
+	if err != nil {
+		return err
+	}
+	user.PassportNumber = parsed.String()
+	existing, found, err := t.storage.GetUserByPassport(user.PassportNumber)
+	if err != nil {
+		return err
+	}
+	if found && existing.Id != user.Id {
+		return ErrDuplicateUser
+	}
+	current, err := t.storage.GetUser(user.Id)
+	if err != nil {
+		return err
+	}
+	user.Status = editedStatus(current.Status)
+	if user.Timezone == "" {
+		user.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(user.Timezone); err != nil {
+		return fmt.Errorf("%w: %q", period.ErrInvalidTimezone, user.Timezone)
+	}
 	return t.storage.UpdateUser(user)
 }
@@ -0,0 +1,53 @@
+package task
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TimeTracker-Effective-Mobile/internal/model"
+	"github.com/TimeTracker-Effective-Mobile/internal/validation"
+)
+
+var ErrDuplicateTeam = errors.New("team already exists")
+
+func (t *taskService) CreateTeam(name string) (model.Team, error) {
+	team := model.Team{Name: strings.TrimSpace(name)}
+	if team.Name == "" || utf8.RuneCountInString(team.Name) > 100 {
+		return team, &validation.Error{Message: "invalid team", Fields: map[string]string{"name": "must be 1 to 100 characters"}}
+	}
+	created, err := t.storage.SaveTeam(&team)
+	if err != nil {
+		return team, err
+	}
+	if !created {
+		return team, ErrDuplicateTeam
+	}
+	return team, nil
+}
+
+func (t *taskService) GetTeams() ([]model.Team, error) {
+	return t.storage.GetTeams()
+}
+
+// SetUserTeam moves the user to the team, or out of any team for nil. The
+// managers of the team can then read the user's reports.
+func (t *taskService) SetUserTeam(userId int, teamId *int) (model.User, error) {
+	if teamId != nil {
+		exists, err := t.storage.TeamExists(*teamId)
+		if err != nil {
+			return model.User{}, err
+		}
+		if !exists {
+			return model.User{}, &validation.Error{Message: "invalid team", Fields: map[string]string{"team_id": "team not exist"}}
+		}
+	}
+	updated, err := t.storage.SetUserTeam(userId, teamId)
+	if err != nil {
+		return model.User{}, err
+	}
+	if !updated {
+		return model.User{}, ErrUserNotFound
+	}
+	return t.storage.GetUser(userId)
+}
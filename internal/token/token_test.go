@@ -0,0 +1,76 @@
+package token
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claims := Claims{Subject: "7", CredentialId: 3, Username: "ivan", OrganizationId: 1, Role: "member", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}
+	valid, err := Sign(claims, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(valid, ".")
+	encode := func(s string) string { return encoding.EncodeToString([]byte(s)) }
+	resigned := func(header, payload string) string {
+		unsigned := encode(header) + "." + encode(payload)
+		return unsigned + "." + encoding.EncodeToString(sign(unsigned, secret))
+	}
+	payload, _ := encoding.DecodeString(parts[1])
+	expired, err := Sign(Claims{CredentialId: 3, OrganizationId: 1, ExpiresAt: now.Unix()}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"valid", valid, nil},
+		{"alg none unsigned", encode(`{"alg":"none","typ":"JWT"}`) + "." + parts[1] + ".", ErrInvalidToken},
+		{"alg none signed", resigned(`{"alg":"none","typ":"JWT"}`, string(payload)), ErrInvalidToken},
+		{"alg changed", resigned(`{"alg":"HS512","typ":"JWT"}`, string(payload)), ErrInvalidToken},
+		{"header swapped", encode(`{"alg":"HS512","typ":"JWT"}`) + "." + parts[1] + "." + parts[2], ErrInvalidToken},
+		{"unexpected typ", resigned(`{"alg":"HS256","typ":"JWE"}`, string(payload)), ErrInvalidToken},
+		{"payload tampered", parts[0] + "." + encode(strings.Replace(string(payload), `"member"`, `"admin"`, 1)) + "." + parts[2], ErrInvalidToken},
+		{"signature tampered", parts[0] + "." + parts[1] + "." + encoding.EncodeToString(make([]byte, 32)), ErrInvalidToken},
+		{"signature missing", parts[0] + "." + parts[1] + ".", ErrInvalidToken},
+		{"expired", expired, ErrExpiredToken},
+		{"empty", "", ErrInvalidToken},
+		{"two segments", parts[0] + "." + parts[1], ErrInvalidToken},
+		{"four segments", valid + ".x", ErrInvalidToken},
+		{"header not base64", "!!." + parts[1] + "." + parts[2], ErrInvalidToken},
+		{"header not json", encode("HS256") + "." + parts[1] + "." + parts[2], ErrInvalidToken},
+		{"signature not base64", parts[0] + "." + parts[1] + ".!!", ErrInvalidToken},
+		{"payload not json", resigned(header, "claims"), ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Verify(tt.token, secret, now)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && got != claims {
+				t.Errorf("Verify() = %+v, want %+v", got, claims)
+			}
+		})
+	}
+}
+
+func TestVerifyWrongSecret(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signed, err := Sign(Claims{CredentialId: 3, OrganizationId: 1, ExpiresAt: now.Add(time.Minute).Unix()}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(signed, []byte("another secret of at least 32 bytes"), now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrInvalidToken)
+	}
+}
@@ -0,0 +1,26 @@
+// Package validation carries field-level input errors from services to the
+// router.
+package validation
+
+import (
+	"sort"
+	"strings"
+)
+
+type Error struct {
+	Message string
+	Fields  map[string]string
+}
+
+func (e *Error) Error() string {
+	keys := make([]string, 0, len(e.Fields))
+	for key := range e.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+": "+e.Fields[key])
+	}
+	return e.Message + ": " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,28 @@
+-- Values stay encrypted: decrypt them with the application before rolling back.
+DROP TABLE people_cache;
+CREATE TABLE people_cache (
+	passport_number varchar(50) PRIMARY KEY,
+	payload jsonb,
+	not_found boolean NOT NULL DEFAULT FALSE,
+	expires_at timestamptz NOT NULL
+);
+
+DROP INDEX IF EXISTS idx_user_search_trgm;
+DROP INDEX IF EXISTS idx_user_search;
+ALTER TABLE users DROP COLUMN IF EXISTS search, DROP COLUMN IF EXISTS search_text;
+
+ALTER TABLE users
+	ADD COLUMN search_text text GENERATED ALWAYS AS (
+		coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' || coalesce(patronymic, '') || ' ' || coalesce(address, '')
+	) STORED,
+	ADD COLUMN search tsvector GENERATED ALWAYS AS (
+		to_tsvector('russian', coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' || coalesce(patronymic, '') || ' ' || coalesce(address, ''))
+		|| to_tsvector('english', coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' || coalesce(patronymic, '') || ' ' || coalesce(address, ''))
+	) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_user_search ON users USING gin(search);
+CREATE INDEX IF NOT EXISTS idx_user_search_trgm ON users USING gin(search_text gin_trgm_ops);
+
+DROP INDEX IF EXISTS idx_user_passport_index;
+ALTER TABLE users DROP COLUMN IF EXISTS passport_index;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_passport ON users(passport_number);
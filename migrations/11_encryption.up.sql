@@ -0,0 +1,37 @@
+-- Passport numbers and addresses become ciphertext, written by the application
+-- (plaintext rows are encrypted on the next start). Lookups and uniqueness
+-- move to the keyed blind index in passport_index.
+DROP INDEX IF EXISTS idx_user_passport;
+
+ALTER TABLE users
+	ALTER COLUMN passport_number TYPE text,
+	ALTER COLUMN address TYPE text,
+	ADD COLUMN IF NOT EXISTS passport_index varchar(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_passport_index ON users(passport_index);
+
+-- Ciphertext can't be searched: rebuild the search columns without the address.
+DROP INDEX IF EXISTS idx_user_search_trgm;
+DROP INDEX IF EXISTS idx_user_search;
+ALTER TABLE users DROP COLUMN IF EXISTS search, DROP COLUMN IF EXISTS search_text;
+
+ALTER TABLE users
+	ADD COLUMN search_text text GENERATED ALWAYS AS (
+		coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' || coalesce(patronymic, '')
+	) STORED,
+	ADD COLUMN search tsvector GENERATED ALWAYS AS (
+		to_tsvector('russian', coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' || coalesce(patronymic, ''))
+		|| to_tsvector('english', coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' || coalesce(patronymic, ''))
+	) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_user_search ON users USING gin(search);
+CREATE INDEX IF NOT EXISTS idx_user_search_trgm ON users USING gin(search_text gin_trgm_ops);
+
+-- The cache holds plaintext lookups; drop them rather than migrate.
+DROP TABLE people_cache;
+CREATE TABLE people_cache (
+	passport_index varchar(64) PRIMARY KEY,
+	payload text,
+	not_found boolean NOT NULL DEFAULT FALSE,
+	expires_at timestamptz NOT NULL
+);
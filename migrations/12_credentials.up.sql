@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS credentials (
+	id serial PRIMARY KEY,
+	username varchar(100) NOT NULL UNIQUE,
+	password_hash varchar(100) NOT NULL,
+	user_id int REFERENCES users(id) ON DELETE CASCADE,
+	admin boolean NOT NULL DEFAULT FALSE,
+	created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
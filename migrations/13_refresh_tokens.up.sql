@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id serial PRIMARY KEY,
+	credential_id int NOT NULL REFERENCES credentials(id) ON DELETE CASCADE,
+	token_hash char(64) NOT NULL UNIQUE,
+	expires_at timestamptz NOT NULL,
+	revoked_at timestamptz,
+	created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_credential_id_idx ON refresh_tokens (credential_id);
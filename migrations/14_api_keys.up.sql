@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+	id serial PRIMARY KEY,
+	credential_id int NOT NULL REFERENCES credentials(id) ON DELETE CASCADE,
+	name varchar(100) NOT NULL,
+	prefix varchar(16) NOT NULL,
+	key_hash char(64) NOT NULL UNIQUE,
+	scopes text[] NOT NULL,
+	expires_at timestamptz,
+	last_used_at timestamptz,
+	created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_credential_id_idx ON api_keys (credential_id);
@@ -0,0 +1,9 @@
+ALTER TABLE credentials ADD COLUMN admin boolean NOT NULL DEFAULT FALSE;
+
+UPDATE credentials SET admin = TRUE WHERE role = 'admin';
+
+ALTER TABLE credentials DROP COLUMN role;
+
+ALTER TABLE users DROP COLUMN team_id;
+
+DROP TABLE teams;
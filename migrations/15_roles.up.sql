@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS teams (
+	id serial PRIMARY KEY,
+	name varchar(100) NOT NULL UNIQUE
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS team_id int REFERENCES teams(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS users_team_id_idx ON users (team_id);
+
+ALTER TABLE credentials ADD COLUMN IF NOT EXISTS role varchar(16) NOT NULL DEFAULT 'member'
+	CHECK (role IN ('admin', 'manager', 'member'));
+
+UPDATE credentials SET role = 'admin' WHERE admin;
+
+ALTER TABLE credentials DROP COLUMN admin;
@@ -0,0 +1,19 @@
+ALTER TABLE users DROP CONSTRAINT users_team_organization_id_fkey;
+ALTER TABLE users ADD CONSTRAINT users_team_id_fkey FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE SET NULL;
+ALTER TABLE teams DROP CONSTRAINT teams_id_organization_id_key;
+ALTER TABLE teams DROP CONSTRAINT teams_organization_id_name_key;
+ALTER TABLE teams DROP COLUMN organization_id;
+ALTER TABLE teams ADD CONSTRAINT teams_name_key UNIQUE (name);
+
+ALTER TABLE credentials DROP COLUMN organization_id;
+
+ALTER TABLE absences DROP COLUMN organization_id;
+
+ALTER TABLE tasks DROP COLUMN organization_id;
+
+DROP INDEX IF EXISTS idx_user_passport_index;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_passport_index ON users(passport_index);
+
+ALTER TABLE users DROP COLUMN organization_id;
+
+DROP TABLE organizations;
@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS organizations (
+	id serial PRIMARY KEY,
+	name varchar(100) NOT NULL UNIQUE,
+	created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Everything that exists so far belongs to the first organization.
+INSERT INTO organizations (id, name) VALUES (1, 'Default') ON CONFLICT DO NOTHING;
+SELECT setval('organizations_id_seq', (SELECT max(id) FROM organizations));
+
+ALTER TABLE users ADD COLUMN organization_id int NOT NULL DEFAULT 1 REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE users ALTER COLUMN organization_id DROP DEFAULT;
+ALTER TABLE users ADD CONSTRAINT users_id_organization_id_key UNIQUE (id, organization_id);
+CREATE INDEX IF NOT EXISTS users_organization_id_idx ON users (organization_id);
+
+-- The same person may work for several organizations.
+DROP INDEX IF EXISTS idx_user_passport_index;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_passport_index ON users(organization_id, passport_index);
+
+-- Rows referencing a user carry the user's organization; the composite keys
+-- make it impossible to point at a user of another organization.
+ALTER TABLE tasks ADD COLUMN organization_id int;
+UPDATE tasks SET organization_id = u.organization_id FROM users u WHERE u.id = tasks.owner;
+-- Tasks without an owner stay with the first organization, like the rest.
+UPDATE tasks SET organization_id = 1 WHERE organization_id IS NULL;
+ALTER TABLE tasks ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE tasks ADD CONSTRAINT tasks_owner_organization_id_fkey
+	FOREIGN KEY (owner, organization_id) REFERENCES users(id, organization_id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS tasks_organization_id_idx ON tasks (organization_id);
+
+ALTER TABLE absences ADD COLUMN organization_id int NOT NULL DEFAULT 1 REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE absences ALTER COLUMN organization_id DROP DEFAULT;
+ALTER TABLE absences ADD CONSTRAINT absences_user_organization_id_fkey
+	FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id) ON DELETE CASCADE;
+
+ALTER TABLE credentials ADD COLUMN organization_id int NOT NULL DEFAULT 1 REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE credentials ALTER COLUMN organization_id DROP DEFAULT;
+ALTER TABLE credentials ADD CONSTRAINT credentials_user_organization_id_fkey
+	FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id) ON DELETE CASCADE;
+
+ALTER TABLE teams ADD COLUMN organization_id int NOT NULL DEFAULT 1 REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE teams ALTER COLUMN organization_id DROP DEFAULT;
+ALTER TABLE teams DROP CONSTRAINT teams_name_key;
+ALTER TABLE teams ADD CONSTRAINT teams_organization_id_name_key UNIQUE (organization_id, name);
+ALTER TABLE teams ADD CONSTRAINT teams_id_organization_id_key UNIQUE (id, organization_id);
+ALTER TABLE users DROP CONSTRAINT users_team_id_fkey;
+ALTER TABLE users ADD CONSTRAINT users_team_organization_id_fkey
+	FOREIGN KEY (team_id, organization_id) REFERENCES teams(id, organization_id);
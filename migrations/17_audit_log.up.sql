@@ -0,0 +1,15 @@
+-- Who did what to which user. Records keep no personal values, only the
+-- names of changed fields, so anonymization leaves them as they are.
+CREATE TABLE IF NOT EXISTS audit_log (
+	id bigserial PRIMARY KEY,
+	organization_id int NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+	user_id int REFERENCES users(id) ON DELETE SET NULL,
+	actor varchar(100) NOT NULL,
+	credential_id int REFERENCES credentials(id) ON DELETE SET NULL,
+	api_key_id int REFERENCES api_keys(id) ON DELETE SET NULL,
+	action varchar(50) NOT NULL,
+	details jsonb NOT NULL DEFAULT '{}',
+	created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_id_idx ON audit_log (user_id, created_at);
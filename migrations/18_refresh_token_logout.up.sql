@@ -0,0 +1,3 @@
+-- Tokens revoked by logout are told apart from rotated ones, whose reuse
+-- means the token leaked.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS logged_out boolean NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+drop index idx_session_task;
+DROP TABLE task_sessions;
+DROP TABLE schedules;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS schedules (
+	user_id int PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	weekly_hours int NOT NULL DEFAULT 40,
+	work_days int[] NOT NULL DEFAULT '{1,2,3,4,5}',
+	day_start time NOT NULL DEFAULT '09:00',
+	day_end time NOT NULL DEFAULT '18:00',
+	timezone varchar(64) NOT NULL DEFAULT 'UTC'
+);
+
+CREATE TABLE IF NOT EXISTS task_sessions (
+	id serial PRIMARY KEY,
+	task_id int REFERENCES tasks(id) ON DELETE CASCADE,
+	started_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	stopped_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_task ON task_sessions(task_id);
+
+-- Work done before sessions existed is only known as the accumulated
+-- duration, which ended when the task was last stopped or resumed.
+INSERT INTO task_sessions (task_id, started_at, stopped_at)
+	SELECT id, updated_at - make_interval(secs => duration), updated_at FROM tasks WHERE duration > 0;
+INSERT INTO task_sessions (task_id, started_at) SELECT id, updated_at FROM tasks WHERE active;
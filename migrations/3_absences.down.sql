@@ -0,0 +1,4 @@
+drop index idx_absence_region;
+drop index idx_absence_user;
+DROP TABLE absences;
+ALTER TABLE schedules DROP COLUMN region;
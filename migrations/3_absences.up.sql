@@ -0,0 +1,15 @@
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS region varchar(64) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS absences (
+	id serial PRIMARY KEY,
+	user_id int REFERENCES users(id) ON DELETE CASCADE,
+	region varchar(64) NOT NULL DEFAULT '',
+	kind varchar(32) NOT NULL,
+	date_from date NOT NULL,
+	date_to date NOT NULL,
+	note varchar(250) NOT NULL DEFAULT '',
+	CHECK (date_from <= date_to)
+);
+
+CREATE INDEX IF NOT EXISTS idx_absence_user ON absences(user_id);
+CREATE INDEX IF NOT EXISTS idx_absence_region ON absences(region) WHERE user_id IS NULL;
@@ -0,0 +1,9 @@
+ALTER TABLE task_sessions
+	ALTER COLUMN started_at TYPE timestamp USING started_at AT TIME ZONE current_setting('TimeZone'),
+	ALTER COLUMN stopped_at TYPE timestamp USING stopped_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE tasks
+	ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE current_setting('TimeZone'),
+	ALTER COLUMN updated_at TYPE timestamp USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE users DROP COLUMN timezone;
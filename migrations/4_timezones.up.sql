@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone varchar(64) NOT NULL DEFAULT 'UTC';
+
+ALTER TABLE tasks
+	ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE current_setting('TimeZone'),
+	ALTER COLUMN updated_at TYPE timestamptz USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE task_sessions
+	ALTER COLUMN started_at TYPE timestamptz USING started_at AT TIME ZONE current_setting('TimeZone'),
+	ALTER COLUMN stopped_at TYPE timestamptz USING stopped_at AT TIME ZONE current_setting('TimeZone');
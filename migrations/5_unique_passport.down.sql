@@ -0,0 +1 @@
+drop index idx_user_passport;
@@ -0,0 +1,29 @@
+UPDATE users SET passport_number = substr(n.digits, 1, 4) || ' ' || substr(n.digits, 5)
+FROM (SELECT id, regexp_replace(passport_number, '[^0-9]', '', 'g') AS digits FROM users) n
+WHERE users.id = n.id AND length(n.digits) = 10;
+
+CREATE TEMP TABLE duplicate_users AS
+	SELECT id, min(id) OVER (PARTITION BY passport_number) AS keeper FROM users;
+DELETE FROM duplicate_users WHERE id = keeper;
+
+-- Users registered twice are merged into the first one: tasks and absences
+-- move over, and so does the oldest schedule when the first user has none.
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM duplicate_users) THEN
+		RAISE NOTICE 'merging duplicate users % into the first user with the same passport',
+			(SELECT string_agg(id::text, ', ' ORDER BY id) FROM duplicate_users);
+	END IF;
+END $$;
+
+UPDATE tasks SET owner = d.keeper FROM duplicate_users d WHERE tasks.owner = d.id;
+UPDATE absences SET user_id = d.keeper FROM duplicate_users d WHERE absences.user_id = d.id;
+INSERT INTO schedules (user_id, weekly_hours, work_days, day_start, day_end, timezone, region)
+	SELECT DISTINCT ON (d.keeper) d.keeper, s.weekly_hours, s.work_days, s.day_start, s.day_end, s.timezone, s.region
+	FROM schedules s JOIN duplicate_users d ON s.user_id = d.id
+	ORDER BY d.keeper, d.id
+	ON CONFLICT (user_id) DO NOTHING;
+DELETE FROM users WHERE id IN (SELECT id FROM duplicate_users);
+DROP TABLE duplicate_users;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_passport ON users(passport_number);
@@ -0,0 +1,2 @@
+drop index idx_user_pending;
+ALTER TABLE users DROP COLUMN status;
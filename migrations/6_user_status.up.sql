@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status varchar(16) NOT NULL DEFAULT 'active';
+
+CREATE INDEX IF NOT EXISTS idx_user_pending ON users(status) WHERE status = 'pending';
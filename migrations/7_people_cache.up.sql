@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS people_cache (
+	passport_number varchar(50) PRIMARY KEY,
+	payload jsonb,
+	not_found boolean NOT NULL DEFAULT FALSE,
+	expires_at timestamptz NOT NULL
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_task_name_trgm;
+DROP INDEX IF EXISTS idx_task_search;
+DROP INDEX IF EXISTS idx_user_search_trgm;
+DROP INDEX IF EXISTS idx_user_search;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS search;
+ALTER TABLE users DROP COLUMN IF EXISTS search, DROP COLUMN IF EXISTS search_text;
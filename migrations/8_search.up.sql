@@ -0,0 +1,20 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE users
+	ADD COLUMN IF NOT EXISTS search_text text GENERATED ALWAYS AS (
+		coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' || coalesce(patronymic, '') || ' ' || coalesce(address, '')
+	) STORED,
+	ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+		to_tsvector('russian', coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' || coalesce(patronymic, '') || ' ' || coalesce(address, ''))
+		|| to_tsvector('english', coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' || coalesce(patronymic, '') || ' ' || coalesce(address, ''))
+	) STORED;
+
+ALTER TABLE tasks
+	ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+		to_tsvector('russian', coalesce(name, '')) || to_tsvector('english', coalesce(name, ''))
+	) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_user_search ON users USING gin(search);
+CREATE INDEX IF NOT EXISTS idx_user_search_trgm ON users USING gin(search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_task_search ON tasks USING gin(search);
+CREATE INDEX IF NOT EXISTS idx_task_name_trgm ON tasks USING gin(name gin_trgm_ops);
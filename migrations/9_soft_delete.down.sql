@@ -0,0 +1,11 @@
+-- Without the column deleted users would silently come back, so they have to
+-- be restored or purged first.
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM users WHERE deleted_at IS NOT NULL) THEN
+		RAISE EXCEPTION 'users are soft-deleted; restore or purge them before rolling back';
+	END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_user_deleted;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;